@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/abhirupda/algopeeps/internal/config"
+	"github.com/abhirupda/algopeeps/internal/eval"
+)
+
+// runEval scores the agents' prompts against fixtures with known problems
+func runEval(args []string) error {
+	flags := flag.NewFlagSet("eval", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: algopeeps eval [flags] [fixtures]")
+		fmt.Fprintln(flags.Output(), "Reviews every fixture in the directory (default testdata/evals) and scores each agent's findings.")
+		flags.PrintDefaults()
+	}
+	server := addServerFlags(flags)
+	var versions []eval.Version
+	flags.Func("prompts", "prompt templates to evaluate, as `name=dir` or dir; repeat to compare versions, name= for the built-in ones (default the configured templates)", func(s string) error {
+		versions = append(versions, eval.ParseVersion(s))
+		return nil
+	})
+	quiet := flags.Bool("quiet", false, "don't report progress on stderr")
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("expected at most one fixture directory")
+	}
+	dir := "testdata/evals"
+	if flags.NArg() == 1 {
+		dir = flags.Arg(0)
+	}
+	fixtures, err := eval.LoadFixtures(dir)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		versions = []eval.Version{{Name: "current", Dir: config.PromptsDir()}}
+	}
+
+	baseURL, stop, err := server.start()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	client, err := connect(baseURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	scores := eval.Run(client, fixtures, versions, progressWriter(*quiet))
+	eval.WriteTable(os.Stdout, scores)
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/lsp"
+)
+
+// runLSP serves the council as a language server over stdio
+func runLSP(args []string) error {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	server := addServerFlags(flags)
+	debounce := flags.Duration("debounce", 5*time.Second, "idle time after an edit before the council reviews it")
+	_ = flags.Parse(args)
+
+	baseURL, stop, err := server.start()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	backends, err := agentBackends(baseURL)
+	if err != nil {
+		return err
+	}
+	defer backends.Close()
+
+	return lsp.New(os.Stdin, os.Stdout, backends, *debounce).Run()
+}
@@ -1,33 +1,166 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 
+	"github.com/abhirupda/algopeeps/internal/backend"
+	"github.com/abhirupda/algopeeps/internal/config"
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/history"
+	"github.com/abhirupda/algopeeps/internal/logging"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/replay"
 	"github.com/abhirupda/algopeeps/internal/server"
 	"github.com/abhirupda/algopeeps/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the command line and returns the exit code, once its deferred
+// cleanup, flushing the log among it, has run
+func run() int {
+	council.SetTemplateDir(config.PromptsDir())
+	recent, stopLogging := setupLogging()
+	defer stopLogging()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lsp":
+			if err := runLSP(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error running language server: %v\n", err)
+				return 1
+			}
+			return 0
+		case "review":
+			code, err := runReview(os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reviewing changes: %v\n", err)
+			}
+			return code
+		case "scan":
+			code, err := runScan(os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error scanning: %v\n", err)
+			}
+			return code
+		case "eval":
+			if err := runEval(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error evaluating prompts: %v\n", err)
+				return 1
+			}
+			return 0
+		case "replay":
+			if err := runReplay(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Error replaying: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	flags := flag.NewFlagSet("algopeeps", flag.ExitOnError)
+	opencodeServer := addServerFlags(flags)
+	record := flags.String("record", "", "record what editors send to this file, for algopeeps replay")
+	_ = flags.Parse(os.Args[1:])
+
+	baseURL, stopOpenCode, err := opencodeServer.start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error starting OpenCode: %v\n", err)
+		return 1
+	}
+	defer stopOpenCode()
+
 	tcpServer := server.New(":9999")
+	if *record != "" {
+		file, err := os.Create(*record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		tcpServer.SetRecorder(replay.NewRecorder(file))
+	}
+
+	model := tui.NewModel(opencode.Config{BaseURL: baseURL})
+	agentsConfig, err := backend.LoadConfig(config.AgentsFile())
+	if err == nil {
+		err = model.SetAgentBackends(agentsConfig)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring agents: %v\n", err)
+		return 1
+	}
+	model.SetEditorSink(tcpServer)
+	model.SetReportsDir(config.ReportsDir())
+	if recent != nil {
+		model.SetLog(recent, config.LogFile())
+	}
+
+	h, err := openHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: history disabled: %v\n", err)
+	} else {
+		model.SetHistory(h)
+		defer h.Close()
+	}
 
-	model := tui.NewModel()
 	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	tcpServer.SetProgram(p)
+	model.WatchLog(p)
 
 	model.StartSSESubscription(p)
 
 	if err := tcpServer.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error starting TCP server: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running program: %v\n", err)
-		os.Exit(1)
+		return 1
 	}
 
 	_ = tcpServer.Stop()
+	return 0
+}
+
+// setupLogging logs to the algopeeps log file at the level $ALGOPEEPS_LOG_LEVEL
+// names (info by default) and keeps the latest warnings and errors for the
+// log pane. Without a log file nothing is logged and recent is nil.
+func setupLogging() (recent *logging.Recent, stop func()) {
+	var level slog.Level
+	if name := os.Getenv("ALGOPEEPS_LOG_LEVEL"); name != "" {
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring ALGOPEEPS_LOG_LEVEL: %v\n", err)
+		}
+	}
+
+	file, err := logging.OpenFile(config.LogFile(), logging.MaxSize, logging.Keep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: logging disabled: %v\n", err)
+		return nil, func() {}
+	}
+	recent = logging.NewRecent(100)
+	logging.Setup(file, level, recent)
+	return recent, func() { file.Close() }
+}
+
+// openHistory opens the history of the project the TUI was started in
+func openHistory() (*history.Store, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	root := council.DetectProjectDir(cwd).Root
+	if root == "" {
+		root = cwd
+	}
+	return history.Open(config.HistoryDir(), root)
 }
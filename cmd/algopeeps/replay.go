@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/replay"
+)
+
+// runReplay plays a recording made with --record, straight to the council or
+// into a running TUI
+func runReplay(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: algopeeps replay [flags] <recording.jsonl>")
+		fmt.Fprintln(flags.Output(), "Plays editor events recorded with algopeeps --record to the council and reports what it found.")
+		flags.PrintDefaults()
+	}
+	server := addServerFlags(flags)
+	speed := flags.Float64("speed", 1, "playback speed relative to the recording; 0 plays events back to back")
+	to := flags.String("to", "", "send the events to the TUI listening at this address instead, e.g. localhost:9999")
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected one recording")
+	}
+	if *speed < 0 {
+		return fmt.Errorf("negative --speed %v", *speed)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	entries, err := replay.Read(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+
+	if *to != "" {
+		return replayTo(*to, entries, *speed)
+	}
+
+	baseURL, stop, err := server.start()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	client, err := connect(baseURL)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	result, err := replay.Run(client, entries, *speed, os.Stderr)
+	if err != nil {
+		return err
+	}
+	result.WriteReport(os.Stdout)
+
+	fmt.Println()
+	for _, agent := range council.Agents {
+		usage := client.Usage(agent)
+		fmt.Printf("%-14s %d input tokens, %d output tokens, $%.4f\n", agent, usage.InputTokens, usage.OutputTokens, usage.Cost)
+	}
+	return nil
+}
+
+// replayTo sends entries to the TUI at addr over a connection per recorded
+// editor connection, as the editors did
+func replayTo(addr string, entries []replay.Entry, speed float64) error {
+	conns := make(map[int]net.Conn)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	return replay.Play(entries, speed, func(e replay.Entry) error {
+		conn, ok := conns[e.Conn]
+		if !ok {
+			var err error
+			conn, err = net.Dial("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("connecting to %s: %w", addr, err)
+			}
+			conns[e.Conn] = conn
+			// Diagnostics and answers come back; nothing reads them
+			go func() { _, _ = io.Copy(io.Discard, conn) }()
+		}
+		if _, err := conn.Write(append(e.Line, '\n')); err != nil {
+			return fmt.Errorf("sending to %s: %w", addr, err)
+		}
+		return nil
+	})
+}
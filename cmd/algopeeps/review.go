@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/abhirupda/algopeeps/internal/backend"
+	"github.com/abhirupda/algopeeps/internal/config"
+	"github.com/abhirupda/algopeeps/internal/export"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/review"
+)
+
+// Exit codes of algopeeps review and scan
+const (
+	reviewExitClean    = 0
+	reviewExitFindings = 1 // Findings at or above --fail-on
+	reviewExitError    = 2
+)
+
+// runReview reviews staged changes, or the given range, and returns the exit
+// code
+func runReview(args []string) (int, error) {
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: algopeeps review [flags] [<commit> | <commit>..<commit>]")
+		fmt.Fprintln(flags.Output(), "Reviews staged changes, or the changes git diff shows for the given range.")
+		flags.PrintDefaults()
+	}
+	server := addServerFlags(flags)
+	failOn := flags.String("fail-on", "error", "lowest severity that makes the review fail: error, warning, info, hint or never")
+	quiet := flags.Bool("quiet", false, "don't report progress on stderr")
+	output := addOutputFlags(flags)
+	_ = flags.Parse(args)
+
+	threshold, err := parseFailOn(*failOn)
+	if err != nil {
+		return reviewExitError, err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return reviewExitError, fmt.Errorf("expected at most one range")
+	}
+
+	var src review.Source
+	if flags.NArg() == 1 {
+		src, err = review.Range(".", flags.Arg(0))
+	} else {
+		src, err = review.Staged(".")
+	}
+	if err != nil {
+		return reviewExitError, err
+	}
+
+	baseURL, stop, err := server.start()
+	if err != nil {
+		return reviewExitError, err
+	}
+	defer stop()
+
+	client, err := connect(baseURL)
+	if err != nil {
+		return reviewExitError, err
+	}
+	defer client.Close()
+
+	result, err := review.Run(client, src, progressWriter(*quiet))
+	if err != nil {
+		return reviewExitError, err
+	}
+	return report(result, threshold, output)
+}
+
+// parseFailOn reads the --fail-on flag. "never" gives an empty severity.
+func parseFailOn(name string) (findings.Severity, error) {
+	if name == "never" {
+		return "", nil
+	}
+	sev, ok := findings.ParseSeverity(name)
+	if !ok {
+		return "", fmt.Errorf("unknown severity %q for --fail-on", name)
+	}
+	return sev, nil
+}
+
+// connect opens the sessions of the agent backends for the batch commands
+func connect(baseURL string) (*backend.Router, error) {
+	backends, err := agentBackends(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := backends.EnsureSession(); err != nil {
+		backends.Close()
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	return backends, nil
+}
+
+// agentBackends runs the agents on the backends the agents config picks,
+// OpenCode at baseURL by default
+func agentBackends(baseURL string) (*backend.Router, error) {
+	cfg, err := backend.LoadConfig(config.AgentsFile())
+	if err != nil {
+		return nil, err
+	}
+	client, err := opencode.NewClient(opencode.Config{BaseURL: baseURL})
+	if err != nil {
+		return nil, err
+	}
+	backends, err := backend.NewRouter(cfg, client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return backends, nil
+}
+
+func progressWriter(quiet bool) io.Writer {
+	if quiet {
+		return nil
+	}
+	return os.Stderr
+}
+
+// outputFlags are the report flags shared by review and scan
+type outputFlags struct {
+	format string
+	output string
+}
+
+func addOutputFlags(flags *flag.FlagSet) *outputFlags {
+	o := &outputFlags{}
+	flags.StringVar(&o.format, "format", "text", "report format: text, markdown, json or sarif")
+	flags.StringVar(&o.output, "o", "", "write the report to this file instead of stdout")
+	return o
+}
+
+// report writes result as asked by o and returns the exit code for it
+func report(result review.Result, threshold findings.Severity, o *outputFlags) (int, error) {
+	var format export.Format
+	if o.format != "text" {
+		f, ok := export.ParseFormat(o.format)
+		if !ok {
+			return reviewExitError, fmt.Errorf("unknown report format %q", o.format)
+		}
+		format = f
+	}
+
+	var w io.Writer = os.Stdout
+	if o.output != "" {
+		file, err := os.Create(o.output)
+		if err != nil {
+			return reviewExitError, err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	if format == "" {
+		result.WriteReport(w)
+	} else {
+		if err := export.Write(w, format, result.Findings); err != nil {
+			return reviewExitError, err
+		}
+		// Keep machine-readable output clean
+		for _, err := range result.Errors {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+	}
+
+	// A review with gaps can't vouch for the changes
+	if len(result.Errors) > 0 {
+		return reviewExitError, fmt.Errorf("%d reviews failed", len(result.Errors))
+	}
+	if threshold != "" && result.Failed(threshold) {
+		return reviewExitFindings, nil
+	}
+	return reviewExitClean, nil
+}
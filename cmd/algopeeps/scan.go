@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/review"
+)
+
+// runScan reviews whole Go packages and returns the exit code
+func runScan(args []string) (int, error) {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: algopeeps scan [flags] [packages]")
+		fmt.Fprintln(flags.Output(), "Reviews the Go files of packages, e.g. ./internal/... (default ./...).")
+		flags.PrintDefaults()
+	}
+	server := addServerFlags(flags)
+	failOn := flags.String("fail-on", "never", "lowest severity that makes the scan fail: error, warning, info, hint or never")
+	jobs := flags.Int("jobs", 4, "chunks reviewed at once, each in its own session")
+	tests := flags.Bool("tests", false, "review _test.go files too")
+	quiet := flags.Bool("quiet", false, "don't report progress on stderr")
+	output := addOutputFlags(flags)
+	_ = flags.Parse(args)
+
+	threshold, err := parseFailOn(*failOn)
+	if err != nil {
+		return reviewExitError, err
+	}
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	files, err := review.GoFiles(patterns, *tests)
+	if err != nil {
+		return reviewExitError, err
+	}
+	if len(files) == 0 {
+		return reviewExitError, fmt.Errorf("no Go files match %v", patterns)
+	}
+
+	baseURL, stop, err := server.start()
+	if err != nil {
+		return reviewExitError, err
+	}
+	defer stop()
+
+	// Agents see every prompt sent in their session, so each job gets one
+	prompters := make([]council.Prompter, 0, max(*jobs, 1))
+	for range max(*jobs, 1) {
+		client, err := connect(baseURL)
+		if err != nil {
+			return reviewExitError, err
+		}
+		defer client.Close()
+		prompters = append(prompters, client)
+	}
+
+	result, err := review.Scan(prompters, files, progressWriter(*quiet))
+	if err != nil {
+		return reviewExitError, err
+	}
+	return report(result, threshold, output)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/abhirupda/algopeeps/internal/config"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+)
+
+// serverFlags say where the OpenCode server is and whether to start one
+type serverFlags struct {
+	url    string
+	serve  bool
+	config string
+}
+
+func addServerFlags(flags *flag.FlagSet) *serverFlags {
+	s := &serverFlags{}
+	flags.StringVar(&s.url, "opencode-url", opencode.DefaultConfig().BaseURL, "OpenCode server URL")
+	flags.BoolVar(&s.serve, "serve", false, "start opencode serve if nothing answers at --opencode-url")
+	flags.StringVar(&s.config, "opencode-config", "", "config file for the opencode serve started by --serve")
+	return s
+}
+
+// start returns the URL of the OpenCode server, starting one when asked to
+// and none is running. stop shuts down the server started, if any.
+func (s *serverFlags) start() (url string, stop func(), err error) {
+	if !s.serve {
+		return s.url, func() {}, nil
+	}
+
+	log, err := openServerLog()
+	if err != nil {
+		return "", nil, err
+	}
+	cfg := opencode.ServerConfig{Log: log}
+	if s.config != "" {
+		cfg.Args = []string{"--config", s.config}
+	}
+	url, srv, err := opencode.EnsureServer(s.url, cfg)
+	if err != nil {
+		log.Close()
+		return "", nil, fmt.Errorf("%w (output in %s)", err, config.ServerLog())
+	}
+	return url, func() {
+		if srv != nil {
+			_ = srv.Stop()
+		}
+		log.Close()
+	}, nil
+}
+
+func openServerLog() (io.WriteCloser, error) {
+	path := config.ServerLog()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+}
@@ -0,0 +1,154 @@
+// Package backend runs the council's agents, through OpenCode or directly
+// against an OpenAI-compatible model server, chosen per agent
+package backend
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+)
+
+// AgentBackend is something that can run council agents
+type AgentBackend interface {
+	// EnsureSession creates the conversation agents talk in, or checks the
+	// current one still exists
+	EnsureSession() error
+	// NewSession starts a fresh conversation, so no reply draws on earlier
+	// exchanges
+	NewSession() error
+	// Prompt sends prompt to agent and waits for its full reply
+	Prompt(agent, prompt string) (string, error)
+	// Chat is Prompt for a question the user asks, which goes on the
+	// conversation with agent
+	Chat(agent, prompt string) (string, error)
+	// OnDelta makes the backend pass reply text to fn as it streams in
+	OnDelta(fn DeltaFunc)
+	// Abort stops the reply agent is producing
+	Abort(agent string) error
+	// Usage returns what agent has used so far
+	Usage(agent string) Usage
+	Close() error
+}
+
+// DeltaFunc receives a piece of an agent's reply as it streams in
+type DeltaFunc func(agent, text string)
+
+// Usage counts the tokens an agent used, and what they cost when the backend
+// knows
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+}
+
+// Router is the AgentBackend of the whole council, handing each agent to the
+// backend it is configured with
+type Router struct {
+	fallback AgentBackend
+	agents   map[string]AgentBackend
+}
+
+// NewRouter routes the agents cfg configures otherwise to their own backend,
+// and the rest to openCode
+func NewRouter(cfg Config, openCode AgentBackend) (*Router, error) {
+	r := &Router{fallback: openCode, agents: make(map[string]AgentBackend)}
+	for agent, ac := range cfg.Agents {
+		switch ac.Backend {
+		case "", BackendOpenCode:
+		case BackendOpenAI:
+			b, err := NewOpenAI(ac)
+			if err != nil {
+				return nil, &ConfigError{Agent: agent, Err: err}
+			}
+			r.agents[agent] = b
+		default:
+			return nil, &ConfigError{Agent: agent, Err: errUnknownBackend(ac.Backend)}
+		}
+	}
+	return r, nil
+}
+
+// Backend returns the backend agent runs on
+func (r *Router) Backend(agent string) AgentBackend {
+	if b, ok := r.agents[agent]; ok {
+		return b
+	}
+	return r.fallback
+}
+
+// Uses reports whether any council agent runs on b
+func (r *Router) Uses(b AgentBackend) bool {
+	return slices.Contains(r.backends(), b)
+}
+
+// backends returns the backends council agents run on, once each
+func (r *Router) backends() []AgentBackend {
+	var bs []AgentBackend
+	for _, agent := range council.Agents {
+		if b := r.Backend(agent); b != nil && !slices.Contains(bs, b) {
+			bs = append(bs, b)
+		}
+	}
+	return bs
+}
+
+// EnsureSession makes sure every backend in use has a session
+func (r *Router) EnsureSession() error {
+	var errs []error
+	for _, b := range r.backends() {
+		errs = append(errs, b.EnsureSession())
+	}
+	return errors.Join(errs...)
+}
+
+// NewSession starts a fresh conversation on every backend in use
+func (r *Router) NewSession() error {
+	var errs []error
+	for _, b := range r.backends() {
+		errs = append(errs, b.NewSession())
+	}
+	return errors.Join(errs...)
+}
+
+// Prompt sends prompt to agent on its backend
+func (r *Router) Prompt(agent, prompt string) (string, error) {
+	return r.Backend(agent).Prompt(agent, prompt)
+}
+
+// Chat asks agent prompt on its backend
+func (r *Router) Chat(agent, prompt string) (string, error) {
+	return r.Backend(agent).Chat(agent, prompt)
+}
+
+// OnDelta passes reply text of every backend to fn
+func (r *Router) OnDelta(fn DeltaFunc) {
+	if r.fallback != nil {
+		r.fallback.OnDelta(fn)
+	}
+	for _, b := range r.agents {
+		b.OnDelta(fn)
+	}
+}
+
+// Abort stops the reply agent is producing
+func (r *Router) Abort(agent string) error {
+	return r.Backend(agent).Abort(agent)
+}
+
+// Usage returns what agent has used on its backend
+func (r *Router) Usage(agent string) Usage {
+	return r.Backend(agent).Usage(agent)
+}
+
+// Close closes every backend
+func (r *Router) Close() error {
+	var errs []error
+	if r.fallback != nil {
+		errs = append(errs, r.fallback.Close())
+	}
+	for _, b := range r.agents {
+		errs = append(errs, b.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Backend names in the config
+const (
+	BackendOpenCode = "opencode"
+	BackendOpenAI   = "openai"
+)
+
+// Config picks the backend of each agent. Agents it doesn't list run on
+// OpenCode.
+type Config struct {
+	Agents map[string]AgentConfig `json:"agents"`
+}
+
+// AgentConfig says where an agent runs
+type AgentConfig struct {
+	Backend string `json:"backend"` // "opencode" (default) or "openai"
+
+	// For openai
+	BaseURL   string `json:"base_url"`    // e.g. http://localhost:11434/v1
+	Model     string `json:"model"`       // Model name the server knows
+	APIKeyEnv string `json:"api_key_env"` // Environment variable holding the API key, if the server wants one
+	System    string `json:"system"`      // System prompt; the agent's built-in one by default
+}
+
+// ConfigError is an agent configured wrong
+type ConfigError struct {
+	Agent string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("agent %s: %v", e.Agent, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+func errUnknownBackend(name string) error {
+	return fmt.Errorf("unknown backend %q, expected %q or %q", name, BackendOpenCode, BackendOpenAI)
+}
+
+// LoadConfig reads the agents config at path. A missing file is an empty
+// config, which runs every agent on OpenCode.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("agents config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("agents config %s: %w", path, err)
+	}
+	return cfg, nil
+}
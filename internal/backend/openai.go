@@ -0,0 +1,281 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// maxHistoryTurns is how many questions and answers an agent remembers; older
+// ones are dropped so prompts don't outgrow the model's context
+const maxHistoryTurns = 8
+
+// defaultSystemPrompts are the agents' instructions when the config doesn't
+// give one, the same OpenCode gets from opencode.json
+var defaultSystemPrompts = map[string]string{
+	"code-reviewer": "You are a code review assistant. Watch the live buffer and provide brief observations about code quality, readability, and best practices. Max 2-3 sentences. Be constructive, not pedantic.",
+	"bug-spotter":   "You are a bug detection assistant. Watch the live buffer and identify potential bugs, null pointer risks, edge cases, and error handling gaps. Max 2-3 sentences. Focus on actionable issues.",
+}
+
+// OpenAI runs agents against an OpenAI-compatible chat completions API, such
+// as a local model server. Prompts stand alone; questions asked with Chat
+// carry on a conversation each agent keeps in memory.
+type OpenAI struct {
+	cfg    AgentConfig
+	apiKey string
+	http   *http.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	history map[string][]chatMessage // By agent, without the system prompt
+	usage   map[string]Usage
+	aborts  map[string]abortable // Context of each agent's replies
+	onDelta DeltaFunc
+}
+
+type abortable struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model         string         `json:"model"`
+	Messages      []chatMessage  `json:"messages"`
+	Stream        bool           `json:"stream"`
+	StreamOptions map[string]any `json:"stream_options,omitempty"`
+}
+
+// chatChunk is one event of a streamed chat completion
+type chatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// NewOpenAI creates a backend talking to the server in cfg
+func NewOpenAI(cfg AgentConfig) (*OpenAI, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("openai backend needs base_url")
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("openai backend needs model")
+	}
+	apiKey := ""
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("$%s is not set", cfg.APIKeyEnv)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OpenAI{
+		cfg:     cfg,
+		apiKey:  apiKey,
+		http:    &http.Client{},
+		ctx:     ctx,
+		cancel:  cancel,
+		history: make(map[string][]chatMessage),
+		usage:   make(map[string]Usage),
+		aborts:  make(map[string]abortable),
+	}, nil
+}
+
+// EnsureSession does nothing: conversations live in memory and need no
+// server-side session
+func (o *OpenAI) EnsureSession() error {
+	return nil
+}
+
+// NewSession forgets every agent's conversation
+func (o *OpenAI) NewSession() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clear(o.history)
+	return nil
+}
+
+// Prompt sends prompt to agent on its own and streams the reply. Reviews
+// carry the whole buffer, earlier ones would only crowd the context.
+func (o *OpenAI) Prompt(agent, prompt string) (string, error) {
+	return o.send(agent, prompt, false)
+}
+
+// Chat sends prompt to agent after the agent's conversation so far, and
+// streams the reply
+func (o *OpenAI) Chat(agent, prompt string) (string, error) {
+	return o.send(agent, prompt, true)
+}
+
+// send runs prompt, in agent's conversation when chat is set
+func (o *OpenAI) send(agent, prompt string, chat bool) (string, error) {
+	o.mu.Lock()
+	ctx := o.agentContext(agent)
+	messages := []chatMessage{{Role: "system", Content: o.systemPrompt(agent)}}
+	if chat {
+		messages = append(messages, o.history[agent]...)
+	}
+	messages = append(messages, chatMessage{Role: "user", Content: prompt})
+	onDelta := o.onDelta
+	o.mu.Unlock()
+
+	text, usage, err := o.complete(ctx, agent, messages, onDelta)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	u := o.usage[agent]
+	u.InputTokens += usage.InputTokens
+	u.OutputTokens += usage.OutputTokens
+	o.usage[agent] = u
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", o.cfg.BaseURL, err)
+	}
+	if !chat {
+		return text, nil
+	}
+
+	turns := append(o.history[agent], chatMessage{Role: "user", Content: prompt}, chatMessage{Role: "assistant", Content: text})
+	if len(turns) > 2*maxHistoryTurns {
+		turns = turns[len(turns)-2*maxHistoryTurns:]
+	}
+	o.history[agent] = turns
+	return text, nil
+}
+
+// agentContext returns the context agent's replies run in until Abort; o.mu
+// must be held
+func (o *OpenAI) agentContext(agent string) context.Context {
+	a, ok := o.aborts[agent]
+	if !ok {
+		a.ctx, a.cancel = context.WithCancel(o.ctx)
+		o.aborts[agent] = a
+	}
+	return a.ctx
+}
+
+func (o *OpenAI) systemPrompt(agent string) string {
+	if o.cfg.System != "" {
+		return o.cfg.System
+	}
+	if p, ok := defaultSystemPrompts[agent]; ok {
+		return p
+	}
+	return fmt.Sprintf("You are %s, an agent of a code review council.", agent)
+}
+
+// complete runs one streamed chat completion
+func (o *OpenAI) complete(ctx context.Context, agent string, messages []chatMessage, onDelta DeltaFunc) (string, Usage, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:         o.cfg.Model,
+		Messages:      messages,
+		Stream:        true,
+		StreamOptions: map[string]any{"include_usage": true},
+	})
+	if err != nil {
+		return "", Usage{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(o.cfg.BaseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", Usage{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	res, err := o.http.Do(req)
+	if err != nil {
+		return "", Usage{}, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return "", Usage{}, fmt.Errorf("%s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+
+	var text strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return text.String(), usage, fmt.Errorf("bad stream event: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+		}
+		for _, choice := range chunk.Choices {
+			if delta := choice.Delta.Content; delta != "" {
+				text.WriteString(delta)
+				if onDelta != nil {
+					onDelta(agent, delta)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return text.String(), usage, err
+	}
+	return text.String(), usage, nil
+}
+
+// OnDelta passes reply text to fn as it streams in
+func (o *OpenAI) OnDelta(fn DeltaFunc) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.onDelta = fn
+}
+
+// Abort stops the replies agent is producing, if any
+func (o *OpenAI) Abort(agent string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if a, ok := o.aborts[agent]; ok {
+		a.cancel()
+		delete(o.aborts, agent)
+	}
+	return nil
+}
+
+// Usage returns the tokens agent used; the server doesn't say what they cost
+func (o *OpenAI) Usage(agent string) Usage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.usage[agent]
+}
+
+// Close aborts every reply in progress
+func (o *OpenAI) Close() error {
+	o.cancel()
+	return nil
+}
@@ -1 +1,84 @@
 package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the algopeeps configuration directory,
+// $XDG_CONFIG_HOME/algopeeps (~/.config/algopeeps by default)
+func Dir() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".config", "algopeeps")
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, "algopeeps")
+}
+
+// PromptsDir returns the directory prompt templates are loaded from. It can
+// be overridden with $ALGOPEEPS_PROMPTS.
+func PromptsDir() string {
+	if dir := os.Getenv("ALGOPEEPS_PROMPTS"); dir != "" {
+		return dir
+	}
+	return filepath.Join(Dir(), "prompts")
+}
+
+// DataDir returns the directory algopeeps keeps its data in,
+// $XDG_DATA_HOME/algopeeps (~/.local/share/algopeeps by default)
+func DataDir() string {
+	base := os.Getenv("XDG_DATA_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".local", "share", "algopeeps")
+		}
+		base = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(base, "algopeeps")
+}
+
+// StateDir returns the directory algopeeps keeps its logs in,
+// $XDG_STATE_HOME/algopeeps (~/.local/state/algopeeps by default)
+func StateDir() string {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(".local", "state", "algopeeps")
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "algopeeps")
+}
+
+// LogFile returns the file algopeeps logs to
+func LogFile() string {
+	return filepath.Join(StateDir(), "algopeeps.log")
+}
+
+// ReportsDir returns the directory the TUI exports findings to
+func ReportsDir() string {
+	return filepath.Join(DataDir(), "reports")
+}
+
+// HistoryDir returns the directory the history of each project is kept in
+func HistoryDir() string {
+	return filepath.Join(DataDir(), "history")
+}
+
+// ServerLog returns the file the output of an opencode serve algopeeps
+// started goes to
+func ServerLog() string {
+	return filepath.Join(StateDir(), "opencode-serve.log")
+}
+
+// AgentsFile returns the config file choosing the backend each agent runs
+// on
+func AgentsFile() string {
+	return filepath.Join(Dir(), "agents.json")
+}
@@ -0,0 +1,347 @@
+package council
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// contextThreshold is the buffer size above which prompts only carry the code
+// around the cursor instead of the whole buffer
+const contextThreshold = 16 * 1024 // 16KB
+
+// maxBlockLines caps the block an indentation-based extraction keeps; larger
+// blocks are cut around the cursor
+const maxBlockLines = 300
+
+// span is a 1-based inclusive range of lines to keep
+type span struct {
+	start, end int
+	label      string
+}
+
+// ExtractContext reduces content to what an agent needs to reason about the
+// code at cursorLine. Go sources keep the package clause, imports, the
+// declaration enclosing the cursor and the top-level declarations it refers
+// to. Other languages keep their import-like header and the indentation block
+// around the cursor. Each kept section is preceded by the lines it came from.
+func ExtractContext(content, filetype, path string, cursorLine int) string {
+	lines := strings.Split(content, "\n")
+
+	var spans []span
+	if filetype == "go" || strings.HasSuffix(path, ".go") {
+		spans = goSpans(content, cursorLine)
+	}
+	if spans == nil {
+		spans = indentSpans(lines, cursorLine)
+	}
+
+	return renderSpans(lines, spans)
+}
+
+// goSpans picks the sections of a Go file relevant to cursorLine. It returns
+// nil when the cursor is not inside a top-level declaration.
+func goSpans(content string, cursorLine int) []span {
+	fset := token.NewFileSet()
+	// Buffers are often mid-edit; the parser still returns what it could
+	file, _ := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return nil
+	}
+
+	lineOf := func(pos token.Pos) int { return fset.Position(pos).Line }
+	declSpan := func(decl ast.Decl, label string) span {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		return span{start: lineOf(start), end: lineOf(decl.End()), label: label}
+	}
+
+	// Index top-level declarations by the names they declare
+	topLevel := make(map[string]ast.Decl)
+	var enclosing ast.Decl
+	for _, decl := range file.Decls {
+		if lineOf(decl.Pos()) <= cursorLine && cursorLine <= lineOf(decl.End()) {
+			enclosing = decl
+		}
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				topLevel[d.Name.Name] = d
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					topLevel[s.Name.Name] = d
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						topLevel[name.Name] = d
+					}
+				}
+			}
+		}
+	}
+	if enclosing == nil {
+		return nil
+	}
+	if gen, ok := enclosing.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+		return nil
+	}
+
+	spans := []span{{start: lineOf(file.Package), end: lineOf(file.Name.End()), label: "package"}}
+	for _, imp := range file.Decls {
+		if gen, ok := imp.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			spans = append(spans, span{start: lineOf(gen.Pos()), end: lineOf(gen.End()), label: "imports"})
+		}
+	}
+
+	// Declarations the enclosing one refers to, signatures only for functions
+	seen := map[ast.Decl]bool{enclosing: true}
+	ast.Inspect(enclosing, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		decl, ok := topLevel[ident.Name]
+		if !ok || seen[decl] {
+			return true
+		}
+		seen[decl] = true
+
+		s := declSpan(decl, "referenced")
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			s.end = lineOf(fn.Body.Lbrace)
+			s.label = "referenced, signature only"
+		}
+		spans = append(spans, s)
+		return true
+	})
+
+	enclosingSpan := declSpan(enclosing, "at cursor")
+	if enclosingSpan.end-enclosingSpan.start+1 > maxBlockLines {
+		enclosingSpan = cursorWindow(cursorLine, enclosingSpan)
+	}
+	return append(spans, enclosingSpan)
+}
+
+// importLineRe matches lines that pull in other code in common languages
+var importLineRe = regexp.MustCompile(`^\s*(import|from\s+\S+\s+import|#include|require|use|using|package|(local|const|let|var)\s+\w+\s*=\s*require)\b`)
+
+// indentSpans picks the import-like header and the top-level indentation
+// block around cursorLine
+func indentSpans(lines []string, cursorLine int) []span {
+	total := len(lines)
+	cursor := min(max(cursorLine, 1), total)
+
+	var spans []span
+	for i, line := range lines {
+		if i+1 >= cursor {
+			break
+		}
+		if importLineRe.MatchString(line) {
+			spans = append(spans, span{start: i + 1, end: i + 1, label: "header"})
+		}
+	}
+
+	isTopLevel := func(i int) bool {
+		line := lines[i-1]
+		return strings.TrimSpace(line) != "" && line[0] != ' ' && line[0] != '\t'
+	}
+
+	// Walk up to the line that opens the block
+	start := cursor
+	for start > 1 && !isTopLevel(start) {
+		start--
+	}
+	// Walk down to the next top-level line; keep it when it closes the block
+	end := cursor
+	for end < total && !isTopLevel(end+1) {
+		end++
+	}
+	if end < total && isClosing(lines[end]) {
+		end++
+	}
+
+	block := span{start: start, end: end, label: "at cursor"}
+	if end-start+1 > maxBlockLines {
+		block = cursorWindow(cursor, block)
+	}
+	return append(spans, block)
+}
+
+// isClosing reports whether a top-level line only closes a block
+func isClosing(line string) bool {
+	switch strings.TrimSpace(line) {
+	case "}", "};", "end", "})", "});", "]", "),", ")":
+		return true
+	}
+	return false
+}
+
+// cursorWindow cuts s down to maxBlockLines centred on cursorLine
+func cursorWindow(cursorLine int, s span) span {
+	half := maxBlockLines / 2
+	start := max(s.start, cursorLine-half)
+	end := min(s.end, start+maxBlockLines-1)
+	return span{start: start, end: end, label: s.label + ", around cursor"}
+}
+
+// renderSpans writes the kept sections in file order, merging overlaps
+func renderSpans(lines []string, spans []span) string {
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var merged []span
+	for _, s := range spans {
+		s.start = max(s.start, 1)
+		s.end = min(s.end, len(lines))
+		if s.start > s.end {
+			continue
+		}
+		if n := len(merged); n > 0 && s.start <= merged[n-1].end+1 {
+			if s.end > merged[n-1].end {
+				merged[n-1].end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	var result strings.Builder
+	for _, s := range merged {
+		result.WriteString(fmt.Sprintf("[lines %d-%d: %s]\n", s.start, s.end, s.label))
+		for i := s.start; i <= s.end; i++ {
+			result.WriteString(lines[i-1])
+			result.WriteString("\n")
+		}
+	}
+	if n := len(merged); n > 0 && merged[n-1].end < len(lines) {
+		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", len(lines)-merged[n-1].end))
+	}
+
+	return result.String()
+}
+
+// Chunks splits content into line ranges small enough to review whole, each
+// at most contextThreshold bytes where possible. Ranges break between
+// top-level declarations in Go and between top-level blocks otherwise; a
+// single declaration over the limit is cut into consecutive windows.
+func Chunks(content, filetype, path string) []protocol.Range {
+	lines := strings.Split(content, "\n")
+	if n := len(lines); n > 1 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if len(content) <= contextThreshold {
+		return []protocol.Range{lineSpan(1, len(lines))}
+	}
+
+	var starts []int
+	if filetype == "go" || strings.HasSuffix(path, ".go") {
+		starts = goDeclStarts(content)
+	}
+	if starts == nil {
+		starts = blockStarts(lines)
+	}
+	starts = append(append([]int{1}, starts...), len(lines)+1)
+
+	// offsets[i] is the size of the first i lines
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line) + 1
+	}
+	size := func(start, end int) int { return offsets[end] - offsets[start-1] }
+
+	var chunks []protocol.Range
+	chunkStart := 1
+	for i := 1; i < len(starts); i++ {
+		end := starts[i] - 1
+		if size(chunkStart, end) <= contextThreshold {
+			continue
+		}
+		// Close the chunk before this unit, then cut the unit if it alone
+		// is too large
+		if prev := starts[i-1] - 1; prev >= chunkStart {
+			chunks = append(chunks, lineSpan(chunkStart, prev))
+			chunkStart = prev + 1
+		}
+		for size(chunkStart, end) > contextThreshold {
+			cut := chunkStart
+			for cut < end && size(chunkStart, cut+1) <= contextThreshold {
+				cut++
+			}
+			chunks = append(chunks, lineSpan(chunkStart, cut))
+			chunkStart = cut + 1
+		}
+	}
+	if chunkStart <= len(lines) {
+		chunks = append(chunks, lineSpan(chunkStart, len(lines)))
+	}
+	return chunks
+}
+
+// goDeclStarts returns the first line of every top-level declaration after
+// the first, doc comments included
+func goDeclStarts(content string) []int {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil || len(file.Decls) < 2 {
+		return nil
+	}
+
+	var starts []int
+	for _, decl := range file.Decls[1:] {
+		pos := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Doc != nil {
+				pos = d.Doc.Pos()
+			}
+		}
+		starts = append(starts, fset.Position(pos).Line)
+	}
+	return starts
+}
+
+// blockStarts returns the lines opening a top-level block after a blank line
+func blockStarts(lines []string) []int {
+	var starts []int
+	for i := 1; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(lines[i-1]) == "" && strings.TrimSpace(line) != "" &&
+			line[0] != ' ' && line[0] != '\t' && !isClosing(line) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func lineSpan(start, end int) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: start},
+		End:   protocol.Position{Line: end},
+	}
+}
@@ -0,0 +1,355 @@
+package council
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// Agents are the agents every buffer event is sent to
+var Agents = []string{"code-reviewer", "bug-spotter"}
+
+// maxContentSize is the prompt content size above which content is truncated
+// around the cursor
+const maxContentSize = 100 * 1024 // 100KB
+
+// Request is a buffer snapshot to put in front of the council
+type Request struct {
+	Path       string
+	Filename   string
+	Filetype   string
+	CursorLine int
+	CursorCol  int
+	Event      string
+	Content    string
+	Selection  *protocol.Range // Lines the user highlighted, if any
+	Diff       string          // Change under review; the buffer's git diff when empty
+}
+
+// Prompter sends a prompt to an agent and returns its reply
+type Prompter interface {
+	Prompt(agent, prompt string) (string, error)
+}
+
+// Response is one agent's reply to a Request
+type Response struct {
+	Agent string
+	Text  string
+	Err   error
+}
+
+// Review sends req to every agent concurrently and returns their replies in
+// Agents order
+func Review(p Prompter, req Request) []Response {
+	rc := gather(req)
+	responses := make([]Response, len(Agents))
+	var wg sync.WaitGroup
+	for i, agent := range Agents {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prompt, err := buildPrompt(agent, req, rc)
+			if prompt == "" {
+				responses[i] = Response{Agent: agent, Err: err}
+				return
+			}
+			text, err := p.Prompt(agent, prompt)
+			responses[i] = Response{Agent: agent, Text: text, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	return responses
+}
+
+// AgentPrompt is the prompt one agent gets for a Request
+type AgentPrompt struct {
+	Agent  string
+	Prompt string
+}
+
+// Prompts renders the prompt of every agent for req, as the dashboard
+// dispatches buffer events. Agents with no prompt for req are left out. The
+// first template error is returned alongside the prompts, see BuildPrompt.
+// It runs git, so keep it off the UI goroutine.
+func Prompts(req Request) ([]AgentPrompt, error) {
+	rc := gather(req)
+	return renderAll(Agents, func(agent string) (string, error) {
+		return buildPrompt(agent, req, rc)
+	})
+}
+
+// QuestionPrompts renders the prompt each of agents gets for question, like
+// Prompts does for buffer events
+func QuestionPrompts(agents []string, req Request, question string) ([]AgentPrompt, error) {
+	rc := gather(req)
+	return renderAll(agents, func(agent string) (string, error) {
+		return buildQuestionPrompt(agent, req, rc, question)
+	})
+}
+
+func renderAll(agents []string, render func(agent string) (string, error)) ([]AgentPrompt, error) {
+	prompts := make([]AgentPrompt, 0, len(agents))
+	var firstErr error
+	for _, agent := range agents {
+		prompt, err := render(agent)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if prompt == "" {
+			continue
+		}
+		prompts = append(prompts, AgentPrompt{Agent: agent, Prompt: prompt})
+	}
+	return prompts, firstErr
+}
+
+// requestContext is what prompts for a request tell about it from outside
+// the buffer. It is gathered once per request and shared by the agents.
+type requestContext struct {
+	project Project
+	diff    func() GitDiff       // Run on first use
+	related func() []RelatedFile // Read on first use
+}
+
+// gather collects the context of req
+func gather(req Request) requestContext {
+	rc := requestContext{project: DetectProject(req.Path)}
+	rc.related = sync.OnceValue(func() []RelatedFile {
+		return relatedFiles(rc.project, req.Path, req.Content)
+	})
+	rc.diff = sync.OnceValue(func() GitDiff {
+		if req.Diff != "" {
+			return GitDiff{Head: req.Diff}
+		}
+		return FileDiff(req.Path, req.Content)
+	})
+	return rc
+}
+
+// selectionContextLines is how much surrounding code a selection review
+// shows on each side of the selection
+const selectionContextLines = 30
+
+// questionEvent selects the template for questions asked directly
+const questionEvent = "question"
+
+// HunkEvent selects the template for reviewing one hunk of a diff outside the
+// editor. Such requests carry the hunk in Diff and its changed lines in
+// Selection.
+const HunkEvent = "review_hunk"
+
+// ScanEvent selects the template for reviewing a part of a file that hasn't
+// necessarily changed, see Chunks. The part is carried in Selection.
+const ScanEvent = "scan"
+
+// templates renders every prompt; see SetTemplateDir
+var templates = NewTemplates("")
+
+// SetTemplateDir loads prompt templates from dir, see Templates
+func SetTemplateDir(dir string) {
+	templates = NewTemplates(dir)
+}
+
+// TemplateDir returns the directory prompt templates are loaded from, see
+// SetTemplateDir
+func TemplateDir() string {
+	return templates.Dir()
+}
+
+// PromptData is what prompt templates can refer to
+type PromptData struct {
+	Agent           string
+	Path            string
+	Filename        string
+	Filetype        string
+	Cursor          protocol.Cursor
+	Event           string
+	Content         string          // Buffer content, truncated around the cursor when large
+	Selection       *protocol.Range // Lines the user highlighted, if any
+	SelectedText    string
+	MarkedSelection string // Numbered lines around the selection, selected ones marked >>>
+	Project         Project
+	Question        string
+
+	FindingInstructions string
+	EditInstructions    string
+
+	diff    func() GitDiff
+	related func() []RelatedFile
+}
+
+// Diff returns the changes to the buffer since the last commit, if tracked
+// by git. Like the branch diff, it only runs git once a template asks.
+func (d PromptData) Diff() string {
+	return d.gitDiff().Head
+}
+
+// BranchDiff returns the changes since the branch forked from DiffBase, when
+// they differ from Diff
+func (d PromptData) BranchDiff() string {
+	return d.gitDiff().Branch
+}
+
+// DiffBase returns the default branch BranchDiff is relative to
+func (d PromptData) DiffBase() string {
+	return d.gitDiff().Base
+}
+
+func (d PromptData) gitDiff() GitDiff {
+	if d.diff == nil {
+		return GitDiff{}
+	}
+	return d.diff()
+}
+
+// Related lists other files of the project worth knowing about. They are only
+// gathered once a template asks for them.
+func (d PromptData) Related() []RelatedFile {
+	if d.related == nil {
+		return nil
+	}
+	return d.related()
+}
+
+// newPromptData fills in the template variables for req
+func newPromptData(agent string, req Request, rc requestContext) PromptData {
+	data := PromptData{
+		Agent:               agent,
+		Path:                req.Path,
+		Filename:            req.Filename,
+		Filetype:            req.Filetype,
+		Cursor:              protocol.Cursor{Line: req.CursorLine, Col: req.CursorCol},
+		Event:               req.Event,
+		Content:             promptContent(req),
+		Selection:           req.Selection,
+		Project:             rc.project,
+		FindingInstructions: findings.FindingInstructions,
+		EditInstructions:    findings.EditInstructions,
+		diff:                rc.diff,
+		related:             rc.related,
+	}
+	if req.Selection != nil {
+		data.SelectedText = SelectedText(req.Content, *req.Selection)
+		data.MarkedSelection = strings.TrimRight(
+			MarkSelection(req.Content, *req.Selection, selectionContextLines), "\n")
+	}
+	return data
+}
+
+// BuildPrompt renders the prompt agent gets for req. When a template from
+// disk is broken the built-in one is used and the error is returned with the
+// prompt.
+func BuildPrompt(agent string, req Request) (string, error) {
+	return buildPrompt(agent, req, gather(req))
+}
+
+func buildPrompt(agent string, req Request, rc requestContext) (string, error) {
+	event := req.Event
+	if (event == string(protocol.EventReviewSelection) || event == HunkEvent || event == ScanEvent) && req.Selection == nil {
+		event = ""
+	}
+	return templates.Render(agent, event, newPromptData(agent, req, rc))
+}
+
+// MarkSelection returns the selected lines of content and contextLines on each
+// side of them, numbered, with the selected lines prefixed by >>>
+func MarkSelection(content string, r protocol.Range, contextLines int) string {
+	lines := strings.Split(content, "\n")
+	totalLines := len(lines)
+
+	start := max(r.Start.Line-1-contextLines, 0)
+	end := min(r.End.Line+contextLines, totalLines)
+	width := len(fmt.Sprint(end))
+
+	var result strings.Builder
+	if start > 0 {
+		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", start))
+	}
+
+	for i := start; i < end; i++ {
+		marker := "   "
+		if i+1 >= r.Start.Line && i+1 <= r.End.Line {
+			marker = ">>>"
+		}
+		result.WriteString(fmt.Sprintf("%s %*d | %s\n", marker, width, i+1, lines[i]))
+	}
+
+	if end < totalLines {
+		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", totalLines-end))
+	}
+
+	return result.String()
+}
+
+// BuildQuestionPrompt renders the prompt for a question the user asks an
+// agent directly, with the buffer attached as context
+func BuildQuestionPrompt(agent string, req Request, question string) (string, error) {
+	return buildQuestionPrompt(agent, req, gather(req), question)
+}
+
+func buildQuestionPrompt(agent string, req Request, rc requestContext, question string) (string, error) {
+	data := newPromptData(agent, req, rc)
+	data.Question = question
+	return templates.Render(agent, questionEvent, data)
+}
+
+// SelectedText returns the lines of content covered by a selection
+func SelectedText(content string, r protocol.Range) string {
+	lines := strings.Split(content, "\n")
+	start := max(r.Start.Line-1, 0)
+	end := min(r.End.Line, len(lines))
+	if start >= end {
+		return ""
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+// promptContent returns the buffer content to embed in a prompt. Buffers over
+// 16KB are reduced to the code around the cursor, see ExtractContext; if that
+// is still over 100KB, 50 lines around the cursor are kept.
+func promptContent(req Request) string {
+	if len(req.Content) <= contextThreshold {
+		return req.Content
+	}
+	if content := ExtractContext(req.Content, req.Filetype, req.Path, req.CursorLine); len(content) <= maxContentSize {
+		return content
+	}
+	return TruncateAroundCursor(req.Content, req.CursorLine, 50)
+}
+
+// TruncateAroundCursor truncates content to keep N lines around the cursor
+func TruncateAroundCursor(content string, cursorLine, contextLines int) string {
+	lines := strings.Split(content, "\n")
+	totalLines := len(lines)
+
+	// Calculate start and end indices
+	start := cursorLine - contextLines
+	if start < 0 {
+		start = 0
+	}
+	end := cursorLine + contextLines
+	if end > totalLines {
+		end = totalLines
+	}
+
+	// Build truncated content
+	var result strings.Builder
+	if start > 0 {
+		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", start))
+	}
+
+	for i := start; i < end; i++ {
+		result.WriteString(lines[i])
+		result.WriteString("\n")
+	}
+
+	if end < totalLines {
+		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", totalLines-end))
+	}
+
+	return result.String()
+}
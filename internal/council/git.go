@@ -0,0 +1,140 @@
+package council
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitTimeout bounds each git command run while building a prompt
+const gitTimeout = 2 * time.Second
+
+// maxDiffSize is the diff size above which it is left out of prompts; such a
+// diff is the whole file more often than not
+const maxDiffSize = 32 * 1024 // 32KB
+
+// defaultBranches are tried in order to find the branch a feature branch
+// forked from
+var defaultBranches = []string{"origin/HEAD", "origin/main", "origin/master", "main", "master"}
+
+// GitDiff holds the changes made to a buffer, in unified diff format. Both are
+// empty when the file isn't tracked by git or nothing changed.
+type GitDiff struct {
+	Head   string // Buffer versus the last commit
+	Branch string // Buffer versus the merge-base with the default branch
+	Base   string // Default branch Branch is relative to, e.g. "origin/main"
+}
+
+// FileDiff diffs content, the possibly unsaved buffer of the file at path,
+// against HEAD and against the point the current branch forked from the
+// default branch. The branch diff is left empty when it would match the HEAD
+// diff.
+func FileDiff(path, content string) GitDiff {
+	if path == "" || !filepath.IsAbs(path) {
+		return GitDiff{}
+	}
+	dir := filepath.Dir(path)
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return GitDiff{}
+	}
+	rel, err := filepath.Rel(strings.TrimSpace(root), path)
+	if err != nil {
+		return GitDiff{}
+	}
+	rel = filepath.ToSlash(rel)
+
+	var diff GitDiff
+	diff.Head = diffRevision(dir, "HEAD", rel, content)
+
+	head, err := git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return diff
+	}
+	for _, branch := range defaultBranches {
+		base, err := git(dir, "merge-base", "HEAD", branch)
+		if err != nil {
+			continue
+		}
+		if base != head {
+			diff.Branch = diffRevision(dir, strings.TrimSpace(base), rel, content)
+			diff.Base = branch
+		}
+		break
+	}
+	if diff.Branch == diff.Head {
+		diff.Branch, diff.Base = "", ""
+	}
+	return diff
+}
+
+// diffRevision diffs content against the file rel as of rev. It returns an
+// empty string when the file doesn't exist at rev, is unchanged, or the diff
+// is too large to be useful.
+func diffRevision(dir, rev, rel, content string) string {
+	old, err := git(dir, "show", rev+":"+rel)
+	if err != nil {
+		return ""
+	}
+	// Editors send buffers without the final newline files end with
+	old, content = withFinalNewline(old), withFinalNewline(content)
+	if old == content {
+		return ""
+	}
+
+	tmp, err := os.MkdirTemp("", "algopeeps-diff-")
+	if err != nil {
+		return ""
+	}
+	defer os.RemoveAll(tmp)
+
+	oldPath := filepath.Join(tmp, "old")
+	newPath := filepath.Join(tmp, "new")
+	if os.WriteFile(oldPath, []byte(old), 0o600) != nil || os.WriteFile(newPath, []byte(content), 0o600) != nil {
+		return ""
+	}
+
+	// --no-index exits 1 when the files differ
+	out, err := git(dir, "diff", "--no-index", "--no-color", "--no-ext-diff", oldPath, newPath)
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return ""
+	}
+
+	// Drop the header naming the temporary files
+	hunks := strings.Index(out, "@@")
+	if hunks < 0 || len(out)-hunks > maxDiffSize {
+		return ""
+	}
+	return fmt.Sprintf("--- a/%s (%s)\n+++ b/%s (buffer)\n%s", rel, rev, rel, strings.TrimRight(out[hunks:], "\n"))
+}
+
+// withFinalNewline ends non-empty s with a newline, adding the one editors
+// leave out of the last line
+func withFinalNewline(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.TrimSuffix(s, "\n") + "\n"
+}
+
+// git runs a git command in dir and returns its stdout
+func git(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,62 @@
+package council
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Project describes the project a buffer belongs to
+type Project struct {
+	Root   string // Directory holding go.mod or .git
+	Name   string // Base name of Root
+	Module string // Go module path, if Root has a go.mod
+}
+
+// DetectProject walks up from path to the nearest directory containing a
+// go.mod or .git. It returns the zero Project when there is none.
+func DetectProject(path string) Project {
+	if path == "" || !filepath.IsAbs(path) {
+		return Project{}
+	}
+	return DetectProjectDir(filepath.Dir(path))
+}
+
+// DetectProjectDir is DetectProject for a directory, which may itself be the
+// project root
+func DetectProjectDir(dir string) Project {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return Project{}
+	}
+
+	for ; ; dir = filepath.Dir(dir) {
+		if module, ok := readModule(filepath.Join(dir, "go.mod")); ok {
+			return Project{Root: dir, Name: filepath.Base(dir), Module: module}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return Project{Root: dir, Name: filepath.Base(dir)}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return Project{}
+		}
+	}
+}
+
+// readModule returns the module path declared in a go.mod file
+func readModule(path string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if module, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), true
+		}
+	}
+	return "", true
+}
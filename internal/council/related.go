@@ -0,0 +1,250 @@
+package council
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RelatedTokenBudget is roughly how many tokens of related files a prompt may
+// carry
+var RelatedTokenBudget = 8000
+
+// bytesPerToken approximates the size of a token in source code
+const bytesPerToken = 4
+
+// importIndexTTL is how long the reverse import index of a module is reused
+const importIndexTTL = 30 * time.Second
+
+// maxIndexedFiles bounds how many files the reverse import index parses
+const maxIndexedFiles = 5000
+
+// RelatedFile is a file shown to agents alongside the buffer
+type RelatedFile struct {
+	Path    string // Relative to the project root
+	Reason  string // Why the file is related, e.g. "test" or "imports this package"
+	Content string
+	Outline bool // Content only has declarations, function bodies are left out
+}
+
+// RelatedFiles gathers the files most likely to matter when reviewing the Go
+// file at path, most relevant first: its _test.go counterpart, the rest of its
+// package, the module packages it imports and the ones importing it. Files are
+// added whole while they fit in RelatedTokenBudget, then as declaration
+// outlines, then not at all; imported packages only ever as outlines. content
+// is the buffer, which may be unsaved.
+func RelatedFiles(path, content string) []RelatedFile {
+	return relatedFiles(DetectProject(path), path, content)
+}
+
+// relatedFiles is RelatedFiles in the already detected project of path
+func relatedFiles(project Project, path, content string) []RelatedFile {
+	if !strings.HasSuffix(path, ".go") || !filepath.IsAbs(path) {
+		return nil
+	}
+	if project.Root == "" {
+		return nil
+	}
+
+	type candidate struct {
+		path    string
+		reason  string
+		outline bool // Only the declarations are interesting
+	}
+	var candidates []candidate
+	seen := map[string]bool{path: true}
+	add := func(p, reason string, outline bool) {
+		if !seen[p] {
+			seen[p] = true
+			candidates = append(candidates, candidate{p, reason, outline})
+		}
+	}
+
+	dir := filepath.Dir(path)
+	if strings.HasSuffix(path, "_test.go") {
+		add(strings.TrimSuffix(path, "_test.go")+".go", "code under test", false)
+	} else {
+		add(strings.TrimSuffix(path, ".go")+"_test.go", "test", false)
+	}
+	for _, p := range goFiles(dir) {
+		add(p, "same package", false)
+	}
+
+	if project.Module != "" {
+		for _, imp := range fileImports(content) {
+			rel, ok := strings.CutPrefix(imp, project.Module)
+			if !ok || (rel != "" && !strings.HasPrefix(rel, "/")) {
+				continue
+			}
+			for _, p := range goFiles(filepath.Join(project.Root, filepath.FromSlash(rel))) {
+				add(p, "imported by this file", true)
+			}
+		}
+
+		if rel, err := filepath.Rel(project.Root, dir); err == nil {
+			pkg := project.Module
+			if rel != "." {
+				pkg += "/" + filepath.ToSlash(rel)
+			}
+			for _, p := range importers.lookup(project, pkg) {
+				add(p, "imports this package", false)
+			}
+		}
+	}
+
+	budget := RelatedTokenBudget * bytesPerToken
+	var result []RelatedFile
+	for _, c := range candidates {
+		data, err := os.ReadFile(c.path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(project.Root, c.path)
+		if err != nil {
+			rel = c.path
+		}
+
+		file := RelatedFile{Path: rel, Reason: c.reason, Content: string(data)}
+		if c.outline || len(file.Content) > budget {
+			file.Content = goOutline(data)
+			file.Outline = true
+		}
+		if file.Content == "" || len(file.Content) > budget {
+			continue
+		}
+		budget -= len(file.Content)
+		result = append(result, file)
+	}
+	return result
+}
+
+// goFiles lists the non-test Go files in dir, except for test counterparts
+// which RelatedFiles adds itself
+func goFiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// fileImports returns the import paths of a Go source
+func fileImports(src string) []string {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ImportsOnly)
+	if file == nil && err != nil {
+		return nil
+	}
+	var paths []string
+	for _, imp := range file.Imports {
+		if p, err := strconv.Unquote(imp.Path.Value); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// goOutline renders a Go file with function bodies removed
+func goOutline(src []byte) string {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil || len(file.Decls) == 0 {
+		return ""
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			fn.Body = nil
+		}
+	}
+
+	var out bytes.Buffer
+	if err := printer.Fprint(&out, fset, file); err != nil {
+		return ""
+	}
+	return out.String()
+}
+
+// importIndex caches, per module, which files import which packages
+type importIndex struct {
+	mu      sync.Mutex
+	modules map[string]*moduleImports
+}
+
+type moduleImports struct {
+	built time.Time
+	// files maps an import path to the non-test files importing it
+	files map[string][]string
+}
+
+var importers = &importIndex{modules: make(map[string]*moduleImports)}
+
+// lookup returns the files in project importing pkg
+func (idx *importIndex) lookup(project Project, pkg string) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	mod, ok := idx.modules[project.Root]
+	if !ok || time.Since(mod.built) > importIndexTTL {
+		mod = buildImportIndex(project)
+		idx.modules[project.Root] = mod
+	}
+
+	files := append([]string(nil), mod.files[pkg]...)
+	sort.Strings(files)
+	return files
+}
+
+func buildImportIndex(project Project) *moduleImports {
+	mod := &moduleImports{built: time.Now(), files: make(map[string][]string)}
+
+	parsed := 0
+	_ = filepath.WalkDir(project.Root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if p != project.Root && (strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata" || name == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+			return nil
+		}
+		if parsed >= maxIndexedFiles {
+			return filepath.SkipAll
+		}
+		parsed++
+
+		file, err := parser.ParseFile(token.NewFileSet(), p, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil
+		}
+		for _, imp := range file.Imports {
+			if ip, err := strconv.Unquote(imp.Path.Value); err == nil && strings.HasPrefix(ip, project.Module) {
+				mod.files[ip] = append(mod.files[ip], p)
+			}
+		}
+		return nil
+	})
+
+	return mod
+}
@@ -0,0 +1,179 @@
+package council
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// builtinTemplates are the prompts used when the template directory doesn't
+// override them
+var builtinTemplates = template.Must(template.ParseFS(builtinPrompts, "prompts/*.tmpl"))
+
+// Templates resolves prompt templates per agent and per event. The template
+// for agent A and event E is the first of
+//
+//	A/E.tmpl, E.tmpl                 in the template directory
+//	E.tmpl                           built in (review_selection, review_hunk, scan, question)
+//	A/default.tmpl, default.tmpl     in the template directory
+//	default.tmpl                     built in
+//
+// Files are re-read when they change on disk, so prompts can be tuned while
+// algopeeps is running.
+type Templates struct {
+	dir string
+
+	mu     sync.Mutex
+	parsed map[string]*cachedTemplate
+}
+
+type cachedTemplate struct {
+	tmpl    *template.Template
+	modTime time.Time
+}
+
+// templateNameRe limits agent and event names used in template paths
+var templateNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// NewTemplates creates a template set backed by dir. An empty dir only uses
+// the built-in templates.
+func NewTemplates(dir string) *Templates {
+	return &Templates{
+		dir:    dir,
+		parsed: make(map[string]*cachedTemplate),
+	}
+}
+
+// Dir returns the directory templates are loaded from
+func (t *Templates) Dir() string {
+	return t.dir
+}
+
+// Render executes the template for agent and event with data. If a template
+// from disk fails to parse or execute, the built-in template is used instead
+// and the error is returned alongside the prompt.
+func (t *Templates) Render(agent, event string, data PromptData) (string, error) {
+	var userErr error
+	tryUser := func(names []string) (string, bool) {
+		tmpl, err := t.lookupUser(names)
+		if err != nil {
+			userErr = err
+			return "", false
+		}
+		if tmpl == nil {
+			return "", false
+		}
+		out, err := execute(tmpl, data)
+		if err != nil {
+			userErr = err
+			return "", false
+		}
+		return out, true
+	}
+
+	specific, fallback := candidates(agent, event)
+	if out, ok := tryUser(specific); ok {
+		return out, nil
+	}
+	builtin, hasBuiltin := t.lookupBuiltin(event)
+	if !hasBuiltin {
+		if out, ok := tryUser(fallback); ok {
+			return out, userErr
+		}
+	}
+
+	out, err := execute(builtin, data)
+	if err != nil {
+		return "", err
+	}
+	return out, userErr
+}
+
+func execute(tmpl *template.Template, data PromptData) (string, error) {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("prompt template %s: %w", tmpl.Name(), err)
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// candidates lists the template files for agent and event, most specific
+// first: the ones written for the event and the defaults
+func candidates(agent, event string) (specific, fallback []string) {
+	validAgent := templateNameRe.MatchString(agent)
+	validEvent := templateNameRe.MatchString(event)
+	if validAgent && validEvent {
+		specific = append(specific, filepath.Join(agent, event+".tmpl"))
+	}
+	if validEvent {
+		specific = append(specific, event+".tmpl")
+	}
+	if validAgent {
+		fallback = append(fallback, filepath.Join(agent, "default.tmpl"))
+	}
+	return specific, append(fallback, "default.tmpl")
+}
+
+func (t *Templates) lookupUser(names []string) (*template.Template, error) {
+	if t.dir == "" {
+		return nil, nil
+	}
+
+	for _, name := range names {
+		path := filepath.Join(t.dir, name)
+		info, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("prompt template %s: %w", path, err)
+		}
+		return t.load(path, info.ModTime())
+	}
+	return nil, nil
+}
+
+// load parses the template at path, reusing the cached parse while the file
+// is unchanged
+func (t *Templates) load(path string, modTime time.Time) (*template.Template, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if cached, ok := t.parsed[path]; ok && cached.modTime.Equal(modTime) {
+		return cached.tmpl, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("prompt template %s: %w", path, err)
+	}
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("prompt template %s: %w", path, err)
+	}
+
+	t.parsed[path] = &cachedTemplate{tmpl: tmpl, modTime: modTime}
+	return tmpl, nil
+}
+
+// lookupBuiltin returns the built-in template for event, or the default
+// one. It reports whether the template was written for event.
+func (t *Templates) lookupBuiltin(event string) (*template.Template, bool) {
+	if templateNameRe.MatchString(event) && event != "default" {
+		if tmpl := builtinTemplates.Lookup(event + ".tmpl"); tmpl != nil {
+			return tmpl, true
+		}
+	}
+	return builtinTemplates.Lookup("default.tmpl"), false
+}
@@ -0,0 +1,210 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+)
+
+// Version is a set of prompt templates to evaluate
+type Version struct {
+	Name string
+	Dir  string // Template directory, see council.SetTemplateDir; empty for the built-in templates
+}
+
+// Score is how well an agent did on the fixtures with one version of the
+// prompts
+type Score struct {
+	Version  string
+	Agent    string
+	Reported int // Findings the agent made
+	Correct  int // Findings matching an expectation
+	Expected int // Expectations the agent should have met
+	Found    int // Expectations the agent met
+	Errors   int // Fixtures the agent failed to review, their expectations counted as missed
+}
+
+// Precision is the share of findings that were expected, 1 when there were
+// none
+func (s Score) Precision() float64 {
+	if s.Reported == 0 {
+		return 1
+	}
+	return float64(s.Correct) / float64(s.Reported)
+}
+
+// Recall is the share of expected findings the agent made, 1 when none were
+// expected
+func (s Score) Recall() float64 {
+	if s.Expected == 0 {
+		return 1
+	}
+	return float64(s.Found) / float64(s.Expected)
+}
+
+// Reviewer is what fixtures are reviewed on
+type Reviewer interface {
+	council.Prompter
+	// NewSession starts a fresh conversation, so no review draws on the
+	// fixtures reviewed before it
+	NewSession() error
+}
+
+// Run reviews every fixture with every version of the prompts and scores
+// each agent per version. Fixtures are dispatched like buffer events in the
+// dashboard: each agent gets the prompt council.Prompts renders, in a session
+// of the fixture's own, and its reply is parsed into findings. What each
+// agent missed and made up is written to progress when it isn't nil. The
+// template directory is restored after.
+func Run(p Reviewer, fixtures []Fixture, versions []Version, progress io.Writer) []Score {
+	if progress == nil {
+		progress = io.Discard
+	}
+	defer council.SetTemplateDir(council.TemplateDir())
+
+	var scores []Score
+	for _, v := range versions {
+		council.SetTemplateDir(v.Dir)
+		byAgent := make(map[string]*Score, len(council.Agents))
+		for _, agent := range council.Agents {
+			byAgent[agent] = &Score{Version: v.Name, Agent: agent}
+		}
+		for _, f := range fixtures {
+			fmt.Fprintf(progress, "%s: %s\n", v.Name, f.Name)
+			runFixture(p, f, byAgent, progress)
+		}
+		for _, agent := range council.Agents {
+			scores = append(scores, *byAgent[agent])
+		}
+	}
+	return scores
+}
+
+// runFixture has every agent review f and adds the outcome to their scores
+func runFixture(p Reviewer, f Fixture, scores map[string]*Score, progress io.Writer) {
+	if err := p.NewSession(); err != nil {
+		for _, agent := range council.Agents {
+			failed(scores[agent], f, fmt.Errorf("session: %w", err), progress)
+		}
+		return
+	}
+
+	req := f.request()
+	prompts, err := council.Prompts(req)
+	if err != nil {
+		fmt.Fprintf(progress, "  warning: %v\n", err)
+	}
+
+	replies := make([]struct {
+		text string
+		err  error
+	}, len(prompts))
+	var wg sync.WaitGroup
+	for i, ap := range prompts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replies[i].text, replies[i].err = p.Prompt(ap.Agent, ap.Prompt)
+		}()
+	}
+	wg.Wait()
+
+	for i, ap := range prompts {
+		score := scores[ap.Agent]
+		if err := replies[i].err; err != nil {
+			failed(score, f, err, progress)
+			continue
+		}
+
+		fs := findings.Parse(ap.Agent, req.Path, replies[i].text, f.Content)
+		var extra []findings.Finding
+		for _, finding := range fs {
+			score.Reported++
+			if matchesAny(f.Expected, finding) {
+				score.Correct++
+			} else {
+				extra = append(extra, finding)
+			}
+		}
+
+		var missed []Expectation
+		expected := 0
+		for _, e := range f.Expected {
+			if !e.expects(ap.Agent) {
+				continue
+			}
+			expected++
+			score.Expected++
+			if found(e, fs) {
+				score.Found++
+			} else {
+				missed = append(missed, e)
+			}
+		}
+
+		fmt.Fprintf(progress, "  %-14s found %d/%d, %d unexpected\n", ap.Agent, expected-len(missed), expected, len(extra))
+		for _, e := range missed {
+			fmt.Fprintf(progress, "    missed     L%d %s\n", e.Line, e.Note)
+		}
+		for _, finding := range extra {
+			fmt.Fprintf(progress, "    unexpected L%d %s\n", finding.Range.Start.Line, finding.Message)
+		}
+	}
+}
+
+// failed scores an agent that couldn't review f as missing everything it
+// should have found
+func failed(score *Score, f Fixture, err error, progress io.Writer) {
+	score.Errors++
+	for _, e := range f.Expected {
+		if e.expects(score.Agent) {
+			score.Expected++
+		}
+	}
+	fmt.Fprintf(progress, "  %-14s failed: %v\n", score.Agent, err)
+}
+
+func matchesAny(expected []Expectation, f findings.Finding) bool {
+	for _, e := range expected {
+		if e.matches(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func found(e Expectation, fs []findings.Finding) bool {
+	for _, f := range fs {
+		if e.matches(f) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTable prints scores as a table with a row per version and agent
+func WriteTable(w io.Writer, scores []Score) {
+	width := len("PROMPTS")
+	for _, s := range scores {
+		width = max(width, len(s.Version))
+	}
+
+	fmt.Fprintf(w, "%-*s  %-14s %9s %7s %7s %8s %6s\n", width, "PROMPTS", "AGENT", "PRECISION", "RECALL", "FOUND", "REPORTED", "ERRORS")
+	for _, s := range scores {
+		fmt.Fprintf(w, "%-*s  %-14s %9.2f %7.2f %7s %8d %6d\n", width, s.Version, s.Agent,
+			s.Precision(), s.Recall(), fmt.Sprintf("%d/%d", s.Found, s.Expected), s.Reported, s.Errors)
+	}
+}
+
+// ParseVersion reads a version given as name=dir, or as a directory named
+// after itself. An empty dir stands for the built-in templates.
+func ParseVersion(s string) Version {
+	if name, dir, ok := strings.Cut(s, "="); ok {
+		return Version{Name: name, Dir: dir}
+	}
+	return Version{Name: s, Dir: s}
+}
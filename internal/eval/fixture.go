@@ -0,0 +1,192 @@
+// Package eval scores agent prompts against code fixtures with known
+// problems, for tuning prompt templates
+package eval
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// ExpectFile is the file in a fixture directory describing it
+const ExpectFile = "expect.json"
+
+// lineSlack is how many lines a finding may be off by and still match an
+// expectation. Agents often point at the line before or after.
+const lineSlack = 1
+
+// Fixture is a file the council reviews and what it should find in it
+type Fixture struct {
+	Name     string // Directory of the fixture
+	Path     string
+	Content  string
+	Filetype string
+	Event    string
+	Cursor   protocol.Cursor
+	Expected []Expectation
+}
+
+// Expectation is a finding a fixture should produce
+type Expectation struct {
+	Line     int      `json:"line"`
+	End      int      `json:"end,omitempty"`      // Last line of the problem, Line when 0
+	Agents   []string `json:"agents,omitempty"`   // Agents expected to find it, every agent when empty
+	Keywords []string `json:"keywords,omitempty"` // The message must contain one of these, when any
+	Note     string   `json:"note,omitempty"`     // What the problem is, for people reading results
+}
+
+// expectFile is the content of ExpectFile
+type expectFile struct {
+	File     string          `json:"file"`  // Defaults to the only other file
+	Event    string          `json:"event"` // Defaults to buffer_write
+	Cursor   protocol.Cursor `json:"cursor"`
+	Findings []Expectation   `json:"findings"`
+}
+
+// LoadFixtures reads every fixture in dir, one per subdirectory holding an
+// ExpectFile, ordered by name
+func LoadFixtures(dir string) ([]Fixture, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %w", err)
+	}
+
+	var fixtures []Fixture
+	for _, entry := range dirEntries {
+		if !entry.IsDir() {
+			continue
+		}
+		fixtureDir := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(fixtureDir, ExpectFile)); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		f, err := LoadFixture(fixtureDir)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures in %s", dir)
+	}
+	return fixtures, nil
+}
+
+// LoadFixture reads the fixture in dir
+func LoadFixture(dir string) (Fixture, error) {
+	name := filepath.Base(dir)
+	data, err := os.ReadFile(filepath.Join(dir, ExpectFile))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %w", name, err)
+	}
+	var expect expectFile
+	if err := json.Unmarshal(data, &expect); err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %s: %w", name, ExpectFile, err)
+	}
+
+	file := expect.File
+	if file == "" {
+		if file, err = onlyFile(dir); err != nil {
+			return Fixture{}, fmt.Errorf("fixture %s: %w", name, err)
+		}
+	}
+	path, err := filepath.Abs(filepath.Join(dir, file))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %w", name, err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %w", name, err)
+	}
+
+	lines := strings.Count(string(content), "\n") + 1
+	for i, e := range expect.Findings {
+		if e.Line < 1 || e.Line > lines || e.End > lines || (e.End != 0 && e.End < e.Line) {
+			return Fixture{}, fmt.Errorf("fixture %s: finding %d: lines %d-%d outside %s", name, i+1, e.Line, e.End, file)
+		}
+		for _, agent := range e.Agents {
+			if !slices.Contains(council.Agents, agent) {
+				return Fixture{}, fmt.Errorf("fixture %s: finding %d: unknown agent %q", name, i+1, agent)
+			}
+		}
+	}
+
+	event := expect.Event
+	if event == "" {
+		event = string(protocol.EventBufferWrite)
+	}
+	return Fixture{
+		Name:     name,
+		Path:     path,
+		Content:  string(content),
+		Filetype: strings.TrimPrefix(filepath.Ext(path), "."),
+		Event:    event,
+		Cursor:   expect.Cursor,
+		Expected: expect.Findings,
+	}, nil
+}
+
+// onlyFile returns the file of a fixture that doesn't name it
+func onlyFile(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && entry.Name() != ExpectFile {
+			files = append(files, entry.Name())
+		}
+	}
+	if len(files) != 1 {
+		return "", fmt.Errorf("%s names no file and there are %d to choose from", ExpectFile, len(files))
+	}
+	return files[0], nil
+}
+
+// request is the buffer event the fixture stands for, as the editor sends it
+func (f Fixture) request() council.Request {
+	return council.Request{
+		Path:       f.Path,
+		Filename:   filepath.Base(f.Path),
+		Filetype:   f.Filetype,
+		CursorLine: f.Cursor.Line,
+		CursorCol:  f.Cursor.Col,
+		Event:      f.Event,
+		Content:    f.Content,
+	}
+}
+
+// expects reports whether agent is expected to make e
+func (e Expectation) expects(agent string) bool {
+	return len(e.Agents) == 0 || slices.Contains(e.Agents, agent)
+}
+
+// matches reports whether finding f is about e
+func (e Expectation) matches(f findings.Finding) bool {
+	end := e.End
+	if end == 0 {
+		end = e.Line
+	}
+	if f.Range.End.Line < e.Line-lineSlack || f.Range.Start.Line > end+lineSlack {
+		return false
+	}
+	if len(e.Keywords) == 0 {
+		return true
+	}
+	message := strings.ToLower(f.Message)
+	for _, keyword := range e.Keywords {
+		if strings.Contains(message, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
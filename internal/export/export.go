@@ -0,0 +1,126 @@
+// Package export writes findings out for people and tools outside algopeeps
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+)
+
+// Format is a report format findings can be exported in
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown" // For PR comments
+	FormatJSON     Format = "json"     // For scripts
+	FormatSARIF    Format = "sarif"    // SARIF 2.1.0, for code scanning tools
+)
+
+// Formats lists every export format
+var Formats = []Format{FormatMarkdown, FormatJSON, FormatSARIF}
+
+// ParseFormat reads a format name, accepting "md" for Markdown
+func ParseFormat(name string) (Format, bool) {
+	switch strings.ToLower(name) {
+	case "markdown", "md":
+		return FormatMarkdown, true
+	case "json":
+		return FormatJSON, true
+	case "sarif":
+		return FormatSARIF, true
+	}
+	return "", false
+}
+
+// Ext returns the file extension for the format, dot included
+func (f Format) Ext() string {
+	switch f {
+	case FormatMarkdown:
+		return ".md"
+	case FormatSARIF:
+		return ".sarif"
+	default:
+		return ".json"
+	}
+}
+
+// Write writes fs to w in format f
+func Write(w io.Writer, f Format, fs []findings.Finding) error {
+	switch f {
+	case FormatMarkdown:
+		return Markdown(w, fs)
+	case FormatJSON:
+		return JSON(w, fs)
+	case FormatSARIF:
+		return SARIF(w, fs)
+	}
+	return fmt.Errorf("unknown export format %q", f)
+}
+
+// Markdown writes fs as a table per file, ready to paste into a PR comment
+func Markdown(w io.Writer, fs []findings.Finding) error {
+	var b strings.Builder
+	b.WriteString("## algopeeps findings\n\n")
+	if len(fs) == 0 {
+		b.WriteString("No findings.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+
+	files := 0
+	for i, f := range fs {
+		if i == 0 || fs[i-1].Path != f.Path {
+			files++
+		}
+	}
+	fmt.Fprintf(&b, "%d findings in %d files: %s\n", len(fs), files, findings.Summary(fs))
+
+	for i, f := range fs {
+		if i == 0 || fs[i-1].Path != f.Path {
+			fmt.Fprintf(&b, "\n### `%s`\n\n", f.Path)
+			b.WriteString("| Line | Severity | Agent | Finding |\n")
+			b.WriteString("|-----:|----------|-------|---------|\n")
+		}
+		line := fmt.Sprint(f.Range.Start.Line)
+		if f.Range.End.Line > f.Range.Start.Line {
+			line += fmt.Sprintf("-%d", f.Range.End.Line)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", line, f.Severity, f.Agent, markdownCell(f.Message))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownCell escapes text for a table cell
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", `\|`)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// jsonReport is the document JSON writes
+type jsonReport struct {
+	Tool        string             `json:"tool"`
+	GeneratedAt time.Time          `json:"generated_at"`
+	Summary     string             `json:"summary"`
+	Findings    []findings.Finding `json:"findings"`
+}
+
+// JSON writes fs as a JSON document with a findings array
+func JSON(w io.Writer, fs []findings.Finding) error {
+	if fs == nil {
+		fs = []findings.Finding{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jsonReport{
+		Tool:        "algopeeps",
+		GeneratedAt: time.Now().UTC(),
+		Summary:     findings.Summary(fs),
+		Findings:    fs,
+	})
+}
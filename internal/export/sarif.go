@@ -0,0 +1,173 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// The subset of SARIF 2.1.0 algopeeps produces
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+// sarifRule is an agent; SARIF results need a rule and agents are the
+// closest thing algopeeps has
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI         string        `json:"uri,omitempty"`
+	URIBaseID   string        `json:"uriBaseId,omitempty"`
+	Description *sarifMessage `json:"description,omitempty"`
+}
+
+// srcRoot is the base of relative paths, the root of the repository they
+// were found in. Its URI is left to the tool reading the log.
+const srcRoot = "%SRCROOT%"
+
+// sarifRegion uses 1-based lines and columns, the end column is exclusive
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// SARIF writes fs as a SARIF 2.1.0 log with one rule per agent
+func SARIF(w io.Writer, fs []findings.Finding) error {
+	agents := make(map[string]bool)
+	results := make([]sarifResult, 0, len(fs))
+	var baseIDs map[string]sarifArtifactLocation
+	for _, f := range fs {
+		agents[f.Agent] = true
+
+		uri, relative := artifactURI(f.Path)
+		location := sarifArtifactLocation{URI: uri}
+		if relative {
+			location.URIBaseID = srcRoot
+			baseIDs = map[string]sarifArtifactLocation{
+				srcRoot: {Description: &sarifMessage{Text: "The root of the repository reviewed"}},
+			}
+		}
+		region := sarifRegion{
+			StartLine:   max(f.Range.Start.Line, 1),
+			StartColumn: f.Range.Start.Col + 1,
+			EndLine:     max(f.Range.End.Line, f.Range.Start.Line, 1),
+		}
+		if f.Range.End.Col > 0 {
+			region.EndColumn = f.Range.End.Col + 1
+		}
+
+		results = append(results, sarifResult{
+			RuleID:  f.Agent,
+			Level:   sarifLevel(f.Severity),
+			Message: sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: location, Region: region},
+			}},
+		})
+	}
+
+	rules := make([]sarifRule, 0, len(agents))
+	for agent := range agents {
+		rules = append(rules, sarifRule{
+			ID:               agent,
+			ShortDescription: sarifMessage{Text: "Findings reported by the " + agent + " agent"},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "algopeeps",
+				InformationURI: "https://github.com/abhirupda/algopeeps",
+				Rules:          rules,
+			}},
+			OriginalURIBaseIDs: baseIDs,
+			Results:            results,
+		}},
+	})
+}
+
+// artifactURI turns a finding path into a SARIF artifact location. Relative
+// paths are left relative to the source root.
+func artifactURI(path string) (uri string, relative bool) {
+	slashed := filepath.ToSlash(path)
+	if !filepath.IsAbs(path) {
+		return (&url.URL{Path: slashed}).String(), true
+	}
+	// Windows paths start with the drive, URI paths with a slash
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String(), false
+}
+
+// sarifLevel maps a severity to a SARIF result level
+func sarifLevel(sev findings.Severity) string {
+	switch sev {
+	case findings.SeverityError:
+		return "error"
+	case findings.SeverityWarning:
+		return "warning"
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,103 @@
+package findings
+
+import (
+	"bufio"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// EditInstructions tells agents how to format a concrete fix so ParseEdits can
+// pick it up.
+const EditInstructions = "If you have a concrete fix, add it as a fenced block:\n" +
+	"```edit L<start>-L<end>\n<replacement lines>\n```"
+
+var editFenceRe = regexp.MustCompile("^```edit\\s+L(\\d+)(?:\\s*-\\s*L?(\\d+))?\\s*$")
+
+// ParseEdits extracts the ```edit blocks from an agent response
+func ParseEdits(agent, path, text string) []protocol.EditProposal {
+	var edits []protocol.EditProposal
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var current *protocol.EditProposal
+	var body []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if current == nil {
+			match := editFenceRe.FindStringSubmatch(strings.TrimSpace(line))
+			if match == nil {
+				continue
+			}
+			start, _ := strconv.Atoi(match[1])
+			end := start
+			if match[2] != "" {
+				end, _ = strconv.Atoi(match[2])
+			}
+			if start < 1 || end < start {
+				continue
+			}
+			current = &protocol.EditProposal{
+				Path:  path,
+				Agent: agent,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: start},
+					End:   protocol.Position{Line: end},
+				},
+			}
+			body = body[:0]
+			continue
+		}
+
+		if strings.TrimSpace(line) == "```" {
+			current.Text = strings.Join(body, "\n")
+			edits = append(edits, *current)
+			current = nil
+			continue
+		}
+		body = append(body, line)
+	}
+
+	return edits
+}
+
+// EditAnchor returns the lines of content edit replaces, so RelocateEdit can
+// follow them as the buffer changes
+func EditAnchor(edit protocol.EditProposal, content string) string {
+	lines := strings.Split(content, "\n")
+	start := min(edit.Range.Start.Line-1, len(lines))
+	end := min(edit.Range.End.Line, len(lines))
+	return strings.Join(lines[start:end], "\n")
+}
+
+// RelocateEdit moves edit to the lines of content closest to its range that
+// still read anchor. It reports false when they are gone, and the edit with
+// them.
+func RelocateEdit(edit protocol.EditProposal, anchor, content string) (protocol.EditProposal, bool) {
+	lines := strings.Split(content, "\n")
+	block := strings.Split(anchor, "\n")
+	matches := func(start int) bool {
+		if start < 1 || start+len(block)-1 > len(lines) {
+			return false
+		}
+		return slices.Equal(lines[start-1:start-1+len(block)], block)
+	}
+
+	want := edit.Range.Start.Line
+	for dist := 0; dist < len(lines); dist++ {
+		for _, start := range []int{want - dist, want + dist} {
+			if matches(start) {
+				delta := start - want
+				edit.Range.Start.Line += delta
+				edit.Range.End.Line += delta
+				return edit, true
+			}
+		}
+	}
+	return edit, false
+}
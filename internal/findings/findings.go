@@ -0,0 +1,201 @@
+package findings
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+	SeverityHint    Severity = "hint"
+)
+
+// FindingInstructions tells agents how to format issues so Parse can pick
+// them up.
+const FindingInstructions = "Report each issue on its own line as:\n" +
+	"- [error|warning|info] L<line>: <message>"
+
+// Finding is a single issue an agent reported against a file
+type Finding struct {
+	Agent    string         `json:"agent"`
+	Path     string         `json:"path"`
+	Range    protocol.Range `json:"range"`
+	Severity Severity       `json:"severity"`
+	Message  string         `json:"message"`
+
+	// anchor is the text of the first line when the finding was reported,
+	// used to follow the line as the buffer changes.
+	anchor string
+}
+
+// Anchor returns the text of the line the finding was reported on, see
+// WithAnchor
+func (f Finding) Anchor() string {
+	return f.anchor
+}
+
+// WithAnchor returns f anchored to the line text anchor, so a finding
+// restored from storage follows its line again
+func (f Finding) WithAnchor(anchor string) Finding {
+	f.anchor = anchor
+	return f
+}
+
+var findingRe = regexp.MustCompile(`(?i)^\s*(?:[-*]\s*)?\[(error|warning|warn|info|hint)\]\s*L(\d+)(?:\s*-\s*L?(\d+))?\s*:\s*(.+)$`)
+
+// Parse extracts findings from an agent response. content is the buffer the
+// agent was shown and anchors each finding to the line it refers to.
+func Parse(agent, path, text, content string) []Finding {
+	lines := strings.Split(content, "\n")
+
+	var result []Finding
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		match := findingRe.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		start, _ := strconv.Atoi(match[2])
+		end := start
+		if match[3] != "" {
+			end, _ = strconv.Atoi(match[3])
+		}
+		if start < 1 || end < start {
+			continue
+		}
+
+		f := Finding{
+			Agent:    agent,
+			Path:     path,
+			Severity: parseSeverity(match[1]),
+			Message:  strings.TrimSpace(match[4]),
+		}
+		if start <= len(lines) {
+			f.anchor = lines[start-1]
+		}
+		f.Range = lineRange(lines, start, end)
+		result = append(result, f)
+	}
+
+	return result
+}
+
+// ParseSeverity reads a severity name, accepting "warn" for warnings. It
+// reports false for names it doesn't know.
+func ParseSeverity(s string) (Severity, bool) {
+	switch strings.ToLower(s) {
+	case "error", "warning", "warn", "info", "hint":
+		return parseSeverity(s), true
+	}
+	return "", false
+}
+
+// AtLeast reports whether s is as severe as min or more
+func (s Severity) AtLeast(min Severity) bool {
+	return s.rank() >= min.rank()
+}
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityInfo:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseSeverity(s string) Severity {
+	switch strings.ToLower(s) {
+	case "error":
+		return SeverityError
+	case "warning", "warn":
+		return SeverityWarning
+	case "hint":
+		return SeverityHint
+	default:
+		return SeverityInfo
+	}
+}
+
+// lineRange spans lines start through end, from the first non-blank column to
+// the end of the last line
+func lineRange(lines []string, start, end int) protocol.Range {
+	r := protocol.Range{
+		Start: protocol.Position{Line: start},
+		End:   protocol.Position{Line: end},
+	}
+	if start <= len(lines) {
+		first := lines[start-1]
+		r.Start.Col = len(first) - len(strings.TrimLeft(first, " \t"))
+	}
+	if end <= len(lines) {
+		r.End.Col = len(lines[end-1])
+	}
+	return r
+}
+
+// Diagnostics converts findings to the diagnostics sent to the editor
+func Diagnostics(fs []Finding) []protocol.Diagnostic {
+	diags := make([]protocol.Diagnostic, 0, len(fs))
+	for _, f := range fs {
+		diags = append(diags, protocol.Diagnostic{
+			Range:    f.Range,
+			Severity: string(f.Severity),
+			Source:   f.Agent,
+			Message:  f.Message,
+		})
+	}
+	return diags
+}
+
+// SortByLocation orders findings by path, line and agent
+func SortByLocation(fs []Finding) {
+	sort.SliceStable(fs, func(i, j int) bool {
+		a, b := fs[i], fs[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Agent < b.Agent
+	})
+}
+
+// Summary counts findings by severity, e.g. "1 error, 2 warnings". It is
+// empty when there are no findings.
+func Summary(fs []Finding) string {
+	counts := make(map[Severity]int)
+	for _, f := range fs {
+		counts[f.Severity]++
+	}
+
+	var parts []string
+	for _, sev := range []Severity{SeverityError, SeverityWarning, SeverityInfo, SeverityHint} {
+		n := counts[sev]
+		switch {
+		case n == 0:
+		case n == 1 || sev == SeverityInfo:
+			parts = append(parts, fmt.Sprintf("%d %s", n, sev))
+		default:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, sev))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
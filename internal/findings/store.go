@@ -0,0 +1,134 @@
+package findings
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store owns the current findings per file and agent. An agent's findings for
+// a file are replaced wholesale when it re-analyzes that file.
+type Store struct {
+	mu    sync.Mutex
+	files map[string]map[string][]Finding
+}
+
+func NewStore() *Store {
+	return &Store{files: make(map[string]map[string][]Finding)}
+}
+
+// Replace sets the findings agent reported for path
+func (s *Store) Replace(path, agent string, fs []Finding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.files[path] == nil {
+		s.files[path] = make(map[string][]Finding)
+	}
+	s.files[path][agent] = slices.Clone(fs)
+}
+
+// File returns every agent's findings for path ordered by line
+func (s *Store) File(path string) []Finding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []Finding
+	for _, fs := range s.files[path] {
+		result = append(result, fs...)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Range.Start.Line != result[j].Range.Start.Line {
+			return result[i].Range.Start.Line < result[j].Range.Start.Line
+		}
+		return result[i].Agent < result[j].Agent
+	})
+	return result
+}
+
+// Agents returns a copy of the findings for path by agent
+func (s *Store) Agents(path string) map[string][]Finding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string][]Finding, len(s.files[path]))
+	for agent, fs := range s.files[path] {
+		result[agent] = slices.Clone(fs)
+	}
+	return result
+}
+
+// All returns every finding in the store ordered by path and line
+func (s *Store) All() []Finding {
+	s.mu.Lock()
+	var result []Finding
+	for _, agents := range s.files {
+		for _, fs := range agents {
+			result = append(result, fs...)
+		}
+	}
+	s.mu.Unlock()
+
+	SortByLocation(result)
+	return result
+}
+
+// Clear forgets every finding for path
+func (s *Store) Clear(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.files, path)
+}
+
+// Prune follows the findings for path to their anchored line in content and
+// drops the ones whose line no longer exists. It reports whether anything
+// changed.
+func (s *Store) Prune(path, content string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agents := s.files[path]
+	if len(agents) == 0 {
+		return false
+	}
+
+	lines := strings.Split(content, "\n")
+	changed := false
+	for agent, fs := range agents {
+		// A new slice: callers may still hold the one Replace was given
+		kept := make([]Finding, 0, len(fs))
+		for _, f := range fs {
+			line, ok := relocate(lines, f.Range.Start.Line, f.anchor)
+			if !ok {
+				changed = true
+				continue
+			}
+			if delta := line - f.Range.Start.Line; delta != 0 {
+				f.Range.Start.Line += delta
+				f.Range.End.Line += delta
+				changed = true
+			}
+			kept = append(kept, f)
+		}
+		agents[agent] = kept
+	}
+	return changed
+}
+
+// relocate finds the line closest to want whose text is anchor
+func relocate(lines []string, want int, anchor string) (int, bool) {
+	if strings.TrimSpace(anchor) == "" {
+		// Blank lines can't be told apart, keep the finding where it is
+		return want, want <= len(lines)
+	}
+	for dist := 0; dist < len(lines); dist++ {
+		if i := want - dist; i >= 1 && i <= len(lines) && lines[i-1] == anchor {
+			return i, true
+		}
+		if i := want + dist; i >= 1 && i <= len(lines) && lines[i-1] == anchor {
+			return i, true
+		}
+	}
+	return 0, false
+}
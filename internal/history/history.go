@@ -0,0 +1,308 @@
+// Package history keeps what the council said about a project across
+// restarts: the OpenCode session it talks in, its latest responses and the
+// findings they contained
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+)
+
+// compactAfter is how many records a project file may grow to before Open
+// rewrites it with only the current state
+const compactAfter = 500
+
+// Store is the history of one project, kept as a JSON-lines file of records
+// appended as things happen
+type Store struct {
+	root string
+	path string
+
+	mu    sync.Mutex
+	file  *os.File
+	state State
+}
+
+// State is the history folded down to what is current
+type State struct {
+	SessionID string
+	Responses map[string]Response                      // By agent
+	Findings  map[string]map[string][]findings.Finding // By path, then agent
+}
+
+// Response is an agent's latest reply
+type Response struct {
+	Agent string    `json:"agent"`
+	Path  string    `json:"path"`
+	Text  string    `json:"text"`
+	Time  time.Time `json:"time"`
+}
+
+type recordType string
+
+const (
+	recordProject  recordType = "project"
+	recordSession  recordType = "session"
+	recordResponse recordType = "response" // A reply and its findings
+	recordFindings recordType = "findings" // Findings alone, after an edit moved them or by compaction
+)
+
+// record is one line of a project file
+type record struct {
+	Type      recordType     `json:"type"`
+	Time      time.Time      `json:"time"`
+	Root      string         `json:"root,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Agent     string         `json:"agent,omitempty"`
+	Path      string         `json:"path,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Findings  []savedFinding `json:"findings,omitempty"`
+}
+
+// savedFinding keeps the anchor findings need to follow their line
+type savedFinding struct {
+	findings.Finding
+	Anchor string `json:"anchor,omitempty"`
+}
+
+// Open loads the history of the project at root from dir, creating it if
+// needed
+func Open(dir, root string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	s := &Store{
+		root:  root,
+		path:  filepath.Join(dir, fileName(root)),
+		state: newState(),
+	}
+
+	records, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	if records > compactAfter {
+		if err := s.compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+	if records == 0 {
+		if err := s.append(record{Type: recordProject, Root: root}); err != nil {
+			s.file.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// fileName names the file of the project at root: readable, but unique per
+// root
+func fileName(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return fmt.Sprintf("%s-%s.jsonl", filepath.Base(root), hex.EncodeToString(sum[:])[:12])
+}
+
+func newState() State {
+	return State{
+		Responses: make(map[string]Response),
+		Findings:  make(map[string]map[string][]findings.Finding),
+	}
+}
+
+// Root returns the project root the store belongs to
+func (s *Store) Root() string {
+	return s.root
+}
+
+// State returns a copy of the current state
+func (s *Store) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := newState()
+	state.SessionID = s.state.SessionID
+	for agent, res := range s.state.Responses {
+		state.Responses[agent] = res
+	}
+	for path, agents := range s.state.Findings {
+		state.Findings[path] = make(map[string][]findings.Finding)
+		for agent, fs := range agents {
+			state.Findings[path][agent] = append([]findings.Finding(nil), fs...)
+		}
+	}
+	return state
+}
+
+// SetSession records the OpenCode session the project talks in. It does
+// nothing when id is already the current session.
+func (s *Store) SetSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == "" || id == s.state.SessionID {
+		return nil
+	}
+	return s.append(record{Type: recordSession, SessionID: id})
+}
+
+// SaveResponse records an agent's reply about path and the findings in it,
+// replacing what the agent said about path before
+func (s *Store) SaveResponse(agent, path, text string, fs []findings.Finding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := make([]savedFinding, 0, len(fs))
+	for _, f := range fs {
+		saved = append(saved, savedFinding{Finding: f, Anchor: f.Anchor()})
+	}
+	return s.append(record{Type: recordResponse, Agent: agent, Path: path, Text: text, Findings: saved})
+}
+
+// SaveFindings records where the findings for path are after an edit moved
+// or dropped them, by agent
+func (s *Store) SaveFindings(path string, byAgent map[string][]findings.Finding) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for agent, fs := range byAgent {
+		saved := make([]savedFinding, 0, len(fs))
+		for _, f := range fs {
+			saved = append(saved, savedFinding{Finding: f, Anchor: f.Anchor()})
+		}
+		if err := s.append(record{Type: recordFindings, Agent: agent, Path: path, Findings: saved}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the project file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// append writes rec and applies it to the state; s.mu must be held
+func (s *Store) append(rec record) error {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	s.apply(rec)
+	return nil
+}
+
+func (s *Store) apply(rec record) {
+	switch rec.Type {
+	case recordSession:
+		s.state.SessionID = rec.SessionID
+	case recordResponse, recordFindings:
+		if rec.Type == recordResponse {
+			s.state.Responses[rec.Agent] = Response{Agent: rec.Agent, Path: rec.Path, Text: rec.Text, Time: rec.Time}
+		}
+		if s.state.Findings[rec.Path] == nil {
+			s.state.Findings[rec.Path] = make(map[string][]findings.Finding)
+		}
+		fs := make([]findings.Finding, 0, len(rec.Findings))
+		for _, f := range rec.Findings {
+			fs = append(fs, f.Finding.WithAnchor(f.Anchor))
+		}
+		s.state.Findings[rec.Path][rec.Agent] = fs
+	}
+}
+
+// load replays the project file and returns how many records it has. Lines
+// that don't parse, e.g. one cut short by a crash, are skipped.
+func (s *Store) load() (int, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("history: %w", err)
+	}
+	defer f.Close()
+
+	records := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		s.apply(rec)
+		records++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("history: %s: %w", s.path, err)
+	}
+	return records, nil
+}
+
+// compact rewrites the project file with just the current state
+func (s *Store) compact() error {
+	records := []record{{Type: recordProject, Time: time.Now(), Root: s.root}}
+	if s.state.SessionID != "" {
+		records = append(records, record{Type: recordSession, Time: time.Now(), SessionID: s.state.SessionID})
+	}
+	for agent, res := range s.state.Responses {
+		records = append(records, record{Type: recordResponse, Time: res.Time, Agent: agent, Path: res.Path, Text: res.Text})
+	}
+	// Findings come after the responses, which would otherwise clear them
+	for path, agents := range s.state.Findings {
+		for agent, fs := range agents {
+			if len(fs) == 0 {
+				continue
+			}
+			saved := make([]savedFinding, 0, len(fs))
+			for _, f := range fs {
+				saved = append(saved, savedFinding{Finding: f, Anchor: f.Anchor()})
+			}
+			records = append(records, record{Type: recordFindings, Time: time.Now(), Agent: agent, Path: path, Findings: saved})
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return fmt.Errorf("history: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("history: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/backend"
+)
+
+// chatServer is an OpenAI-compatible server replying "reply N" to the Nth
+// request and recording how many messages each request carried
+type chatServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	messages []int
+}
+
+func newChatServer(t *testing.T) *chatServer {
+	s := &chatServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []json.RawMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.messages = append(s.messages, len(req.Messages))
+		n := len(s.messages)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": \"reply %d\"}}]}\n\n", n)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestOpenAI_OnlyChatKeepsHistory(t *testing.T) {
+	server := newChatServer(t)
+	b, err := backend.NewOpenAI(backend.AgentConfig{BaseURL: server.URL, Model: "test"})
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	defer b.Close()
+
+	for _, send := range []func(agent, prompt string) (string, error){
+		b.Prompt, b.Prompt, b.Chat, b.Chat, b.Prompt, b.Chat,
+	} {
+		if _, err := send("bug-spotter", "look"); err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+	}
+	if err := b.NewSession(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Chat("bug-spotter", "look"); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+
+	// The system prompt and the prompt, after the questions since NewSession
+	want := []int{2, 2, 2, 4, 2, 6, 2}
+	if fmt.Sprint(server.messages) != fmt.Sprint(want) {
+		t.Errorf("Expected requests with %v messages, got %v", want, server.messages)
+	}
+}
@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// answers returns the answers sent to the editor
+func answers(t *testing.T, sink *editorSink) []protocol.AnswerMessage {
+	t.Helper()
+	var result []protocol.AnswerMessage
+	for _, msg := range sink.sent {
+		if answer, ok := msg.(protocol.AnswerMessage); ok {
+			result = append(result, answer)
+		}
+	}
+	return result
+}
+
+func TestAsk_EmptyQuestionIsAnswered(t *testing.T) {
+	sink := &editorSink{}
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetEditorSink(sink)
+	var model tea.Model = m
+
+	_, cmd := model.Update(tui.QuestionMsg{ID: "q1", Conn: 2, Agent: "bug-spotter", Buffer: e2eBuffer()})
+	if cmd == nil {
+		t.Fatal("Expected the editor answered")
+	}
+	cmd()
+	got := answers(t, sink)
+	if len(got) != 1 || got[0].ID != "q1" || !got[0].Done || got[0].Error != "empty question" {
+		t.Errorf("Expected the question completed with an error, got %+v", got)
+	}
+}
+
+func TestAsk_ReviewDoesNotStreamIntoAnswer(t *testing.T) {
+	sink := &editorSink{}
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetEditorSink(sink)
+	var model tea.Model = m
+
+	model, _ = model.Update(tui.QuestionMsg{ID: "q1", Conn: 2, Agent: "bug-spotter", Question: "Why fmt?", Buffer: e2eBuffer()})
+	model = run(model.Update(opencode.AgentTextMsg{Agent: "bug-spotter", Text: "Because "}))
+
+	// A review of the buffer prompts the agent again before it has answered
+	model, _ = model.Update(viewBufferEvent())
+	model = run(model.Update(opencode.AgentTextMsg{Agent: "bug-spotter", Text: "[warning] L6: ignored error"}))
+	if got := answers(t, sink); len(got) != 1 || got[0].Text != "Because " {
+		t.Errorf("Expected only the answer's own chunk streamed, got %+v", got)
+	}
+
+	// The answer is still completed
+	run(model.Update(tui.ChatReplyMsg{Agent: "bug-spotter", QuestionID: "q1", Conn: 2, Text: "Because it prints."}))
+	got := answers(t, sink)
+	if len(got) != 2 || !got[1].Done || got[1].Text != "Because it prints." {
+		t.Errorf("Expected the answer completed, got %+v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package integration
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+)
+
+const contextSource = `package main
+
+import "fmt"
+
+// limit caps retries
+const limit = 3
+
+// helper doubles n
+func helper(n int) int {
+	return n * 2
+}
+
+func unrelated() {
+	fmt.Println("x")
+}
+
+func main() {
+	for i := 0; i < limit; i++ {
+		fmt.Println(helper(i))
+	}
+}
+`
+
+func TestExtractContext(t *testing.T) {
+	tests := map[string]struct {
+		content, filetype, path string
+		cursor                  int
+		want                    string
+	}{
+		"inside a declaration": {
+			content: contextSource, filetype: "go", path: "main.go", cursor: 19,
+			want: "[lines 1-1: package]\npackage main\n" +
+				"[lines 3-3: imports]\nimport \"fmt\"\n" +
+				"[lines 5-6: referenced]\n// limit caps retries\nconst limit = 3\n" +
+				"[lines 8-9: referenced, signature only]\n// helper doubles n\nfunc helper(n int) int {\n" +
+				"[lines 17-21: at cursor]\nfunc main() {\n\tfor i := 0; i < limit; i++ {\n\t\tfmt.Println(helper(i))\n\t}\n}\n" +
+				"[...1 lines omitted...]\n",
+		},
+		"go detected from the path": {
+			content: contextSource, path: "main.go", cursor: 10,
+			want: "[lines 1-1: package]\npackage main\n" +
+				"[lines 3-3: imports]\nimport \"fmt\"\n" +
+				"[lines 8-11: at cursor]\n// helper doubles n\nfunc helper(n int) int {\n\treturn n * 2\n}\n" +
+				"[...11 lines omitted...]\n",
+		},
+		"between declarations": {
+			content: contextSource, filetype: "go", path: "main.go", cursor: 16,
+			want: "[lines 1-1: header]\npackage main\n" +
+				"[lines 3-3: header]\nimport \"fmt\"\n" +
+				"[lines 15-16: at cursor]\n}\n\n" +
+				"[...6 lines omitted...]\n",
+		},
+		"in the imports": {
+			content: contextSource, filetype: "go", path: "main.go", cursor: 3,
+			want: "[lines 1-1: header]\npackage main\n" +
+				"[lines 3-4: at cursor]\nimport \"fmt\"\n\n" +
+				"[...18 lines omitted...]\n",
+		},
+		"source that doesn't parse": {
+			content:  "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\n\tif x {\n}\n\nfunc other() {\n\treturn\n}\n",
+			filetype: "go", path: "main.go", cursor: 6,
+			want: "[lines 1-1: header]\npackage main\n" +
+				"[lines 3-3: header]\nimport \"fmt\"\n" +
+				"[lines 5-8: at cursor]\nfunc main() {\n\tfmt.Println(\n\tif x {\n}\n" +
+				"[...5 lines omitted...]\n",
+		},
+		"other languages": {
+			content:  "import os\nfrom a import b\n\nx = 1\n\ndef f():\n    if x:\n        return os.getcwd()\n    return b\n\ndef g():\n    pass\n",
+			filetype: "python", path: "a.py", cursor: 8,
+			want: "[lines 1-2: header]\nimport os\nfrom a import b\n" +
+				"[lines 6-10: at cursor]\ndef f():\n    if x:\n        return os.getcwd()\n    return b\n\n" +
+				"[...3 lines omitted...]\n",
+		},
+		"cursor past the end": {
+			content: "a\n  b\n", filetype: "text", path: "a.txt", cursor: 99,
+			want: "[lines 1-3: at cursor]\na\n  b\n\n",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := council.ExtractContext(tt.content, tt.filetype, tt.path, tt.cursor); got != tt.want {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.want, got)
+			}
+		})
+	}
+}
+
+// goFuncs makes a Go file of n documented functions of roughly size bytes
+// each, returning it with the line each function's doc comment is on
+func goFuncs(n, size int) (string, []int) {
+	var b strings.Builder
+	b.WriteString("package big\n")
+	line := 2
+	var starts []int
+	for i := range n {
+		b.WriteString("\n")
+		line++
+		starts = append(starts, line)
+		fmt.Fprintf(&b, "// f%d does nothing\nfunc f%d() {\n", i, i)
+		line += 2
+		for written := 0; written < size; written += 40 {
+			b.WriteString("\t_ = \"................................\"\n")
+			line++
+		}
+		b.WriteString("}\n")
+		line++
+	}
+	return b.String(), starts
+}
+
+func TestChunks(t *testing.T) {
+	funcs, funcStarts := goFuncs(40, 1024)
+	huge, _ := goFuncs(1, 40*1024)
+	broken := strings.Replace(funcs, "func f3() {", "func f3( {", 1)
+
+	tests := map[string]struct {
+		content, filetype string
+		count             int   // Chunks expected, 0 for any
+		starts            []int // Lines chunks may start on besides the first
+	}{
+		"small file":   {content: contextSource, filetype: "go", count: 1},
+		"empty":        {content: "", filetype: "go", count: 1},
+		"declarations": {content: funcs, filetype: "go", starts: funcStarts},
+		// The package clause stays apart, the function is cut into windows
+		"huge declaration": {content: huge, filetype: "go", count: 4},
+		// Blocks start after blank lines like the declarations do
+		"source that doesn't parse": {content: broken, filetype: "go", starts: funcStarts},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			chunks := council.Chunks(tt.content, tt.filetype, "big.go")
+			if tt.count != 0 && len(chunks) != tt.count {
+				t.Fatalf("Expected %d chunks, got %+v", tt.count, chunks)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(tt.content, "\n"), "\n")
+			next := 1
+			for _, c := range chunks {
+				if c.Start.Line != next {
+					t.Fatalf("Expected a chunk starting on line %d, got %+v", next, chunks)
+				}
+				if size := len(strings.Join(lines[c.Start.Line-1:c.End.Line], "\n")) + 1; size > 16*1024 {
+					t.Errorf("Chunk %+v is %d bytes", c, size)
+				}
+				if tt.starts != nil && c.Start.Line != 1 && !contains(tt.starts, c.Start.Line) {
+					t.Errorf("Chunk %+v splits a declaration", c)
+				}
+				next = c.End.Line + 1
+			}
+			if next != len(lines)+1 {
+				t.Errorf("Expected chunks to cover all %d lines, got %+v", len(lines), chunks)
+			}
+		})
+	}
+}
+
+func contains(lines []int, line int) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+)
+
+// gitRepo makes a repository in a temporary directory with files committed
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		writeFile(t, filepath.Join(dir, name), content)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", args[0], err, out)
+		}
+	}
+	return dir
+}
+
+func TestFileDiff_UnchangedBufferWithoutFinalNewline(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	path := filepath.Join(dir, "main.go")
+
+	// Neovim joins the buffer lines, leaving out the final newline
+	if diff := council.FileDiff(path, "package main\n\nfunc main() {}"); diff.Head != "" || diff.Branch != "" {
+		t.Errorf("Expected no diff for an unchanged buffer, got %+v", diff)
+	}
+
+	diff := council.FileDiff(path, "package main\n\nfunc main() { panic(1) }")
+	if !strings.Contains(diff.Head, "+func main() { panic(1) }") {
+		t.Errorf("Expected the changed line in the diff, got %q", diff.Head)
+	}
+	if strings.Contains(diff.Head, "No newline at end of file") {
+		t.Errorf("Expected the final newline to be ignored, got %q", diff.Head)
+	}
+}
+
+func TestBuildPrompt_RelatedFiles(t *testing.T) {
+	dir := gitRepo(t, map[string]string{
+		"go.mod":      "module example.com/calc\n\ngo 1.22\n",
+		"add.go":      "package calc\n\nfunc Add(a, b int) int { return a + b }\n",
+		"add_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n",
+	})
+
+	prompt, err := council.BuildPrompt("code-reviewer", council.Request{
+		Path:     filepath.Join(dir, "add.go"),
+		Filename: "add.go",
+		Filetype: "go",
+		Event:    "buffer_write",
+		Content:  "package calc\n\nfunc Add(a, b int) int { return a - b }\n",
+	})
+	if err != nil {
+		t.Fatalf("BuildPrompt failed: %v", err)
+	}
+	for _, want := range []string{"Related files from the project", "add_test.go", "func TestAdd", "-func Add(a, b int) int { return a + b }"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Expected %q in the prompt, got:\n%s", want, prompt)
+		}
+	}
+}
+
+func TestPrompts_FenceBuffer(t *testing.T) {
+	req := council.Request{
+		Path:     filepath.Join(t.TempDir(), "main.go"),
+		Filename: "main.go",
+		Filetype: "go",
+		Event:    "buffer_change",
+		Content:  e2eContent,
+	}
+	fenced := "```go\n" + e2eContent + "\n```\n"
+
+	review, err := council.BuildPrompt("code-reviewer", req)
+	if err != nil {
+		t.Fatalf("BuildPrompt failed: %v", err)
+	}
+	questions, err := council.QuestionPrompts([]string{"code-reviewer"}, req, "Why main?")
+	if err != nil || len(questions) != 1 {
+		t.Fatalf("QuestionPrompts failed: %v", err)
+	}
+	for name, prompt := range map[string]string{"review": review, "question": questions[0].Prompt} {
+		if !strings.Contains(prompt, fenced) {
+			t.Errorf("Expected the buffer fenced in the %s prompt, got:\n%s", name, prompt)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/opencode/opencodetest"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+	"github.com/abhirupda/algopeeps/internal/server"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// End-to-end tests: an editor connected over TCP, the real TUI model in a
+// running program, and the fake OpenCode server behind it
+
+const e2eContent = "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+
+// step is a message the dashboard handled and its view after
+type step struct {
+	msg  tea.Msg
+	view string
+}
+
+// dashboard wraps the TUI model to report every step it takes
+type dashboard struct {
+	model tui.Model
+	steps chan<- step
+}
+
+func (d dashboard) Init() tea.Cmd { return d.model.Init() }
+
+func (d dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m, cmd := d.model.Update(msg)
+	d.model = m.(tui.Model)
+	d.steps <- step{msg: msg, view: d.model.View()}
+	return d, cmd
+}
+
+func (d dashboard) View() string { return d.model.View() }
+
+// startDashboard runs the TUI and its TCP server against fake, at the given
+// terminal size, and waits until it streams from OpenCode
+func startDashboard(t *testing.T, fake *opencodetest.Server, width, height int) (<-chan step, string) {
+	t.Helper()
+
+	tcpServer := server.New("127.0.0.1:0")
+	model := tui.NewModel(opencode.Config{BaseURL: fake.URL})
+	model.SetEditorSink(tcpServer)
+
+	steps := make(chan step, 1024)
+	p := tea.NewProgram(dashboard{model: model, steps: steps},
+		tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer())
+	tcpServer.SetProgram(p)
+	model.StartSSESubscription(p)
+
+	if err := tcpServer.Start(); err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	go func() { _, _ = p.Run() }()
+	t.Cleanup(func() {
+		// Quitting closes the OpenCode client, ending its lifecycle
+		p.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+		p.Wait()
+		_ = tcpServer.Stop()
+	})
+
+	p.Send(tea.WindowSizeMsg{Width: width, Height: height})
+	waitForStep(t, steps, "OpenCode streaming", func(s step) bool {
+		state, ok := s.msg.(opencode.StateMsg)
+		return ok && state.State == opencode.StateStreaming
+	})
+	return steps, tcpServer.Addr()
+}
+
+// waitForStep returns the first step done satisfies
+func waitForStep(t *testing.T, steps <-chan step, what string, done func(step) bool) step {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case s := <-steps:
+			if done(s) {
+				return s
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+	}
+}
+
+// connectEditor connects to the TCP server like the Neovim plugin and
+// returns the messages the server sends it
+func connectEditor(t *testing.T, addr string, steps <-chan step) (net.Conn, <-chan json.RawMessage) {
+	t.Helper()
+
+	conn, err := dialTCP(addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	waitForStep(t, steps, "the editor connection", func(s step) bool {
+		status, ok := s.msg.(tui.ConnectionStatusMsg)
+		return ok && status.Source == "nvim" && status.Connected
+	})
+
+	received := make(chan json.RawMessage, 256)
+	go func() {
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- json.RawMessage(line)
+		}
+	}()
+	return conn, received
+}
+
+// send writes msg to conn as a JSON line
+func send(t *testing.T, conn net.Conn, msg any) {
+	t.Helper()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to encode message: %v", err)
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+}
+
+// nextOfType returns the next message the editor received with type typ
+func nextOfType(t *testing.T, received <-chan json.RawMessage, typ protocol.MessageType, v any) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case raw, ok := <-received:
+			if !ok {
+				t.Fatalf("Connection closed waiting for a %s message", typ)
+			}
+			var header struct {
+				Type protocol.MessageType `json:"type"`
+			}
+			if json.Unmarshal(raw, &header) == nil && header.Type == typ {
+				if err := json.Unmarshal(raw, v); err != nil {
+					t.Fatalf("Invalid %s message: %v", typ, err)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for a %s message", typ)
+		}
+	}
+}
+
+func e2eBuffer() protocol.Buffer {
+	return protocol.Buffer{
+		ID:        1,
+		Name:      "main.go",
+		Path:      "/e2e/project/main.go",
+		Filetype:  "go",
+		Cursor:    protocol.Cursor{Line: 6, Col: 1},
+		LineCount: 7,
+		Content:   e2eContent,
+	}
+}
+
+func TestE2E_BufferEventRenderedInDashboard(t *testing.T) {
+	fake := opencodetest.NewServer()
+	t.Cleanup(fake.Close)
+	fake.Reply("code-reviewer", "Small and readable, nothing to add.")
+	fake.Reply("bug-spotter", "[warning] L6: the error from fmt.Println is ignored")
+
+	steps, addr := startDashboard(t, fake, 120, 30)
+	conn, received := connectEditor(t, addr, steps)
+
+	send(t, conn, protocol.BufferEvent{
+		Type:      protocol.MessageBufferUpdate,
+		Timestamp: time.Date(2025, 1, 4, 12, 0, 0, 0, time.UTC),
+		Event:     protocol.EventBufferWrite,
+		Buffer:    e2eBuffer(),
+	})
+
+	streamed := make(map[string]string)
+	idle := make(map[string]bool)
+	responded := make(map[string]bool)
+	final := waitForStep(t, steps, "both agents to reply", func(s step) bool {
+		switch msg := s.msg.(type) {
+		case opencode.AgentTextMsg:
+			streamed[msg.Agent] += msg.Text
+		case opencode.AgentIdleMsg:
+			idle[msg.Agent] = true
+		case tui.AgentResponseMsg:
+			responded[msg.Agent] = true
+		}
+		return len(responded) == 2 && idle["code-reviewer"] && idle["bug-spotter"]
+	})
+
+	prompts := fake.Prompts()
+	if len(prompts) != 2 {
+		t.Fatalf("Expected a prompt per agent, got %d", len(prompts))
+	}
+	for _, prompt := range prompts {
+		if !strings.Contains(prompt.Text, "fmt.Println(\"hello\")") {
+			t.Errorf("Expected the prompt to %s to carry the buffer, got %q", prompt.Agent, prompt.Text)
+		}
+	}
+	// Text that streamed before the full reply is a prefix of it
+	if got := streamed["bug-spotter"]; !strings.HasPrefix("[warning] L6: the error from fmt.Println is ignored", got) {
+		t.Errorf("Unexpected text streamed for bug-spotter: %q", got)
+	}
+
+	// Each reply republishes the file's diagnostics; code-reviewer's may
+	// come first, with none
+	var diagnostics protocol.DiagnosticsMessage
+	for len(diagnostics.Diagnostics) == 0 {
+		nextOfType(t, received, protocol.MessageDiagnostics, &diagnostics)
+	}
+	if diagnostics.Path != "/e2e/project/main.go" || len(diagnostics.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic for main.go, got %+v", diagnostics)
+	}
+	if d := diagnostics.Diagnostics[0]; d.Source != "bug-spotter" || d.Range.Start.Line != 6 {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+
+	checkGolden(t, "e2e_buffer_event", final.view)
+}
+
+func TestE2E_FindingsFollowEdits(t *testing.T) {
+	fake := opencodetest.NewServer()
+	t.Cleanup(fake.Close)
+	fake.Reply("code-reviewer", "Small and readable, nothing to add.")
+	fake.Reply("bug-spotter", "[warning] L6: the error from fmt.Println is ignored")
+
+	steps, addr := startDashboard(t, fake, 120, 30)
+	conn, received := connectEditor(t, addr, steps)
+
+	buffer := e2eBuffer()
+	send(t, conn, protocol.BufferEvent{
+		Type:   protocol.MessageBufferUpdate,
+		Event:  protocol.EventBufferWrite,
+		Buffer: buffer,
+	})
+	waitForReplies(t, steps)
+
+	// A comment added above main moves the flagged line down before the
+	// agents have looked at the edit
+	fake.Reply("bug-spotter", "[warning] L7: the error from fmt.Println is ignored")
+	buffer.Content = strings.Replace(e2eContent, "func main", "// main greets\nfunc main", 1)
+	buffer.LineCount++
+	send(t, conn, protocol.BufferEvent{
+		Type:   protocol.MessageBufferUpdate,
+		Event:  protocol.EventTextChanged,
+		Buffer: buffer,
+	})
+
+	// Diagnostics published for the first replies, empty ones included, come
+	// before the edit's
+	var diagnostics protocol.DiagnosticsMessage
+	for len(diagnostics.Diagnostics) == 0 || diagnostics.Diagnostics[0].Range.Start.Line == 6 {
+		nextOfType(t, received, protocol.MessageDiagnostics, &diagnostics)
+	}
+	if len(diagnostics.Diagnostics) != 1 || diagnostics.Diagnostics[0].Range.Start.Line != 7 {
+		t.Fatalf("Expected the diagnostic to move to line 7, got %+v", diagnostics.Diagnostics)
+	}
+	waitForReplies(t, steps)
+
+	// Deleting the line drops it
+	buffer.Content = strings.Replace(buffer.Content, "\tfmt.Println(\"hello\")\n", "", 1)
+	buffer.LineCount--
+	send(t, conn, protocol.BufferEvent{
+		Type:   protocol.MessageBufferUpdate,
+		Event:  protocol.EventTextChanged,
+		Buffer: buffer,
+	})
+	for len(diagnostics.Diagnostics) != 0 {
+		nextOfType(t, received, protocol.MessageDiagnostics, &diagnostics)
+		if len(diagnostics.Diagnostics) != 0 && diagnostics.Diagnostics[0].Range.Start.Line != 7 {
+			t.Fatalf("Unexpected diagnostic %+v", diagnostics.Diagnostics[0])
+		}
+	}
+}
+
+// waitForReplies waits until both agents replied to a buffer event
+func waitForReplies(t *testing.T, steps <-chan step) {
+	t.Helper()
+
+	responded := make(map[string]bool)
+	waitForStep(t, steps, "both agents to reply", func(s step) bool {
+		if msg, ok := s.msg.(tui.AgentResponseMsg); ok {
+			responded[msg.Agent] = true
+		}
+		return len(responded) == 2
+	})
+}
+
+func TestE2E_EditorQuestionAnswered(t *testing.T) {
+	fake := opencodetest.NewServer()
+	t.Cleanup(fake.Close)
+	fake.Reply("bug-spotter", "Println only fails if stdout is closed.")
+
+	steps, addr := startDashboard(t, fake, 120, 30)
+	conn, received := connectEditor(t, addr, steps)
+
+	send(t, conn, protocol.QuestionMessage{
+		Type:      protocol.MessageQuestion,
+		Timestamp: time.Date(2025, 1, 4, 12, 0, 0, 0, time.UTC),
+		ID:        "q-1",
+		Agent:     "bug-spotter",
+		Question:  "Can Println fail here?",
+		Buffer:    e2eBuffer(),
+	})
+
+	final := waitForStep(t, steps, "the answer", func(s step) bool {
+		_, ok := s.msg.(tui.ChatReplyMsg)
+		return ok
+	})
+
+	prompts := fake.Prompts()
+	if len(prompts) != 1 || prompts[0].Agent != "bug-spotter" || !strings.Contains(prompts[0].Text, "Can Println fail here?") {
+		t.Fatalf("Expected the question to reach bug-spotter only, got %+v", prompts)
+	}
+
+	// Chunks may stream first; the done message carries the whole answer
+	var answer protocol.AnswerMessage
+	for !answer.Done {
+		nextOfType(t, received, protocol.MessageAnswer, &answer)
+		if answer.ID != "q-1" || answer.Agent != "bug-spotter" {
+			t.Fatalf("Unexpected answer %+v", answer)
+		}
+	}
+	if answer.Text != "Println only fails if stdout is closed." || answer.Error != "" {
+		t.Errorf("Unexpected final answer %+v", answer)
+	}
+
+	checkGolden(t, "e2e_question", final.view)
+}
+
+func TestE2E_AnswerGoesToAskingEditor(t *testing.T) {
+	fake := opencodetest.NewServer()
+	t.Cleanup(fake.Close)
+	fake.Reply("bug-spotter", "Println only fails if stdout is closed.")
+
+	steps, addr := startDashboard(t, fake, 120, 30)
+	_, other := connectEditor(t, addr, steps)
+	conn, received := connectEditor(t, addr, steps)
+
+	send(t, conn, protocol.QuestionMessage{
+		Type:     protocol.MessageQuestion,
+		ID:       "q-1",
+		Agent:    "bug-spotter",
+		Question: "Can Println fail here?",
+		Buffer:   e2eBuffer(),
+	})
+
+	var answer protocol.AnswerMessage
+	for !answer.Done {
+		nextOfType(t, received, protocol.MessageAnswer, &answer)
+	}
+	waitForStep(t, steps, "the answer", func(s step) bool {
+		_, ok := s.msg.(tui.ChatReplyMsg)
+		return ok
+	})
+
+	// Anything sent to the other editor has arrived by the time the asking
+	// one has the whole answer
+	for {
+		select {
+		case raw := <-other:
+			var header struct {
+				Type protocol.MessageType `json:"type"`
+			}
+			if json.Unmarshal(raw, &header) == nil && header.Type == protocol.MessageAnswer {
+				t.Fatalf("Expected the answer only on the asking connection, got %s", raw)
+			}
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
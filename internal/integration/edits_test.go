@@ -0,0 +1,131 @@
+package integration
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorSink records what the dashboard sends the editor, failing with err
+// when set
+type editorSink struct {
+	err  error
+	sent []any
+}
+
+func (s *editorSink) Broadcast(msg any) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *editorSink) Send(conn int, msg any) error {
+	return s.Broadcast(msg)
+}
+
+// run runs cmd and feeds its message back to model
+func run(model tea.Model, cmd tea.Cmd) tea.Model {
+	if cmd == nil {
+		return model
+	}
+	model, _ = model.Update(cmd())
+	return model
+}
+
+var applyKey = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a")}
+
+func TestApplyEdit_KeptUntilSent(t *testing.T) {
+	sink := &editorSink{err: errors.New("no editor connected")}
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetEditorSink(sink)
+	var model tea.Model = m
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model, _ = model.Update(viewBufferEvent())
+	model, _ = model.Update(tui.AgentResponseMsg{
+		Agent:   "bug-spotter",
+		Path:    "/view/project/main.go",
+		Content: e2eContent,
+		Text: "Import the os package too.\n```edit L3-L3\nimport \"fmt\"\nimport \"os\"\n```\n" +
+			"Write to stderr.\n```edit L6-L6\n\tfmt.Fprintln(os.Stderr, \"hello\")\n```",
+	})
+
+	// Failing to send leaves the edit pending and the buffer as it was
+	model = run(model.Update(applyKey))
+	view := model.View()
+	if !strings.Contains(view, "(2 pending)") || !strings.Contains(view, "main.go L3-L3") {
+		t.Errorf("Expected the edit still pending, got:\n%s", view)
+	}
+	if !strings.Contains(view, "Applying edit: no editor connected") {
+		t.Errorf("Expected the error in the status bar, got:\n%s", view)
+	}
+
+	// Once sent, the next edit moves down by the line the first one added
+	sink.err = nil
+	model = run(model.Update(applyKey))
+	view = model.View()
+	if len(sink.sent) != 1 || sink.sent[0].(protocol.EditMessage).Edit.Range.Start.Line != 3 {
+		t.Fatalf("Expected the first edit sent, got %+v", sink.sent)
+	}
+	if !strings.Contains(view, "(1 pending)") || !strings.Contains(view, "main.go L7-L7") || !strings.Contains(view, "fmt.Println(\"hello\")") {
+		t.Errorf("Expected the second edit shifted onto the edited buffer, got:\n%s", view)
+	}
+}
+
+func TestPendingEdits_FollowBufferEvents(t *testing.T) {
+	sink := &editorSink{}
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetEditorSink(sink)
+	var model tea.Model = m
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	event := viewBufferEvent()
+	model, _ = model.Update(event)
+	model, _ = model.Update(tui.AgentResponseMsg{
+		Agent:   "bug-spotter",
+		Path:    event.Path,
+		Content: e2eContent,
+		Text: "Import the os package too.\n```edit L3-L3\nimport \"fmt\"\nimport \"os\"\n```\n" +
+			"Write to stderr.\n```edit L6-L6\n\tfmt.Fprintln(os.Stderr, \"hello\")\n```",
+	})
+	model = run(model.Update(applyKey))
+
+	// Neovim reports the buffer the edit made, then the cursor leaving it;
+	// neither touches the line the other edit replaces
+	event.Content = strings.Replace(e2eContent, "import \"fmt\"", "import \"fmt\"\nimport \"os\"", 1)
+	event.LineCount++
+	event.LastEvent = "text_changed"
+	model, _ = model.Update(event)
+	event.LastEvent = "buffer_leave"
+	model, _ = model.Update(event)
+	if view := model.View(); !strings.Contains(view, "(1 pending)") || !strings.Contains(view, "main.go L7-L7") {
+		t.Errorf("Expected the other edit still pending on line 7, got:\n%s", view)
+	}
+
+	// A line added above moves it, rewriting its line drops it
+	event.Content = "// Package main greets\n" + event.Content
+	event.LineCount++
+	model, _ = model.Update(event)
+	if view := model.View(); !strings.Contains(view, "main.go L8-L8") {
+		t.Errorf("Expected the edit moved to line 8, got:\n%s", view)
+	}
+	event.Content = strings.Replace(event.Content, "\"hello\"", "\"hi\"", 1)
+	model, _ = model.Update(event)
+	if view := model.View(); strings.Contains(view, "pending)") {
+		t.Errorf("Expected the edit dropped with its line, got:\n%s", view)
+	}
+
+	// A new reply replaces what the agent proposed before
+	model, _ = model.Update(tui.AgentResponseMsg{Agent: "bug-spotter", Path: event.Path, Content: event.Content,
+		Text: "```edit L1-L1\n// Package main says hi\n```"})
+	model, _ = model.Update(tui.AgentResponseMsg{Agent: "bug-spotter", Path: event.Path, Content: event.Content,
+		Text: "```edit L1-L1\n// Package main greets briefly\n```"})
+	if view := model.View(); !strings.Contains(view, "(1 pending)") || !strings.Contains(view, "greets briefly") {
+		t.Errorf("Expected only the latest proposal pending, got:\n%s", view)
+	}
+}
@@ -0,0 +1,176 @@
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/eval"
+	"github.com/abhirupda/algopeeps/internal/opencode/opencodetest"
+)
+
+// evalFixtures are the fixtures shipped with the repository
+const evalFixtures = "../../testdata/evals"
+
+func TestEval_LoadsShippedFixtures(t *testing.T) {
+	fixtures, err := eval.LoadFixtures(evalFixtures)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+	if len(fixtures) < 2 {
+		t.Fatalf("Expected several fixtures, got %d", len(fixtures))
+	}
+	for _, f := range fixtures {
+		if f.Content == "" || f.Filetype != "go" || f.Event != "buffer_write" {
+			t.Errorf("Fixture %s loaded incompletely: %+v", f.Name, f)
+		}
+	}
+}
+
+func TestEval_RejectsExpectationsOutsideFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "broken", "main.go"), "package main\n")
+	writeFile(t, filepath.Join(dir, "broken", eval.ExpectFile), `{"findings": [{"line": 40}]}`)
+
+	if _, err := eval.LoadFixtures(dir); err == nil || !strings.Contains(err.Error(), "outside main.go") {
+		t.Errorf("Expected the expectation to be rejected, got %v", err)
+	}
+}
+
+func TestEval_ScoresAgentsPerPromptVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "leak", "main.go"), "package main\n\nfunc main() {\n\tf, _ := os.Open(\"x\")\n\t_ = f\n}\n")
+	writeFile(t, filepath.Join(dir, "leak", eval.ExpectFile), `{
+		"findings": [{"line": 4, "agents": ["bug-spotter"], "keywords": ["close"], "note": "f is never closed"}]
+	}`)
+	writeFile(t, filepath.Join(dir, "clean", "main.go"), "package main\n\nfunc main() {}\n")
+	writeFile(t, filepath.Join(dir, "clean", eval.ExpectFile), `{"findings": []}`)
+	fixtures, err := eval.LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	// The tuned prompts make bug-spotter find the leak without the false
+	// alarm the built-in ones lead to
+	tuned := t.TempDir()
+	writeFile(t, filepath.Join(tuned, "bug-spotter", "default.tmpl"), "TUNED\n{{.Content}}")
+
+	fake, client := setupOpenCode(t)
+	fake.ReplyFunc(func(agent, prompt string) string {
+		switch {
+		case agent != "bug-spotter":
+			return "[info] L1: consider a package comment"
+		case strings.Contains(prompt, "TUNED") && strings.Contains(prompt, "os.Open"):
+			return "[error] L4: the file is never closed"
+		case strings.Contains(prompt, "TUNED"):
+			return opencodetest.DefaultReply
+		default:
+			return "[warning] L3: main does nothing"
+		}
+	})
+	if err := client.EnsureSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	before := council.TemplateDir()
+	var progress bytes.Buffer
+	scores := eval.Run(client, fixtures, []eval.Version{
+		{Name: "builtin"},
+		{Name: "tuned", Dir: tuned},
+	}, &progress)
+	if council.TemplateDir() != before {
+		t.Errorf("Expected the template directory %q restored, got %q", before, council.TemplateDir())
+	}
+
+	// Each fixture is reviewed in a session of its own, per version
+	sessions := make(map[string]int)
+	for _, prompt := range fake.Prompts() {
+		sessions[prompt.SessionID]++
+	}
+	if len(sessions) != 2*len(fixtures) {
+		t.Errorf("Expected a session per version and fixture, got prompts in %v", sessions)
+	}
+	for id, n := range sessions {
+		if n != len(council.Agents) {
+			t.Errorf("Expected a prompt per agent in session %s, got %d", id, n)
+		}
+	}
+
+	if len(scores) != 2*len(council.Agents) {
+		t.Fatalf("Expected a score per version and agent, got %+v", scores)
+	}
+	want := map[string]eval.Score{
+		"builtin/bug-spotter":   {Reported: 2, Correct: 0, Expected: 1, Found: 0},
+		"tuned/bug-spotter":     {Reported: 1, Correct: 1, Expected: 1, Found: 1},
+		"builtin/code-reviewer": {Reported: 2, Correct: 0, Expected: 0, Found: 0},
+		"tuned/code-reviewer":   {Reported: 2, Correct: 0, Expected: 0, Found: 0},
+	}
+	for _, s := range scores {
+		w, ok := want[s.Version+"/"+s.Agent]
+		if !ok {
+			t.Errorf("Unexpected score %+v", s)
+			continue
+		}
+		if s.Reported != w.Reported || s.Correct != w.Correct || s.Expected != w.Expected || s.Found != w.Found || s.Errors != 0 {
+			t.Errorf("%s/%s: expected %+v, got %+v", s.Version, s.Agent, w, s)
+		}
+	}
+	if !strings.Contains(progress.String(), "missed     L4 f is never closed") {
+		t.Errorf("Expected the missed finding in the progress, got %q", progress.String())
+	}
+
+	var table bytes.Buffer
+	eval.WriteTable(&table, scores)
+	if !strings.Contains(table.String(), "tuned    bug-spotter         1.00    1.00     1/1        1      0") {
+		t.Errorf("Unexpected table:\n%s", table.String())
+	}
+}
+
+// downReviewer is a backend that can't be reached
+type downReviewer struct{}
+
+func (downReviewer) NewSession() error { return nil }
+
+func (downReviewer) Prompt(agent, prompt string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func TestEval_ErrorsCountAsMisses(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "leak", "main.go"), "package main\n\nfunc main() {\n\tf, _ := os.Open(\"x\")\n\t_ = f\n}\n")
+	writeFile(t, filepath.Join(dir, "leak", eval.ExpectFile), `{
+		"findings": [{"line": 4, "agents": ["bug-spotter"], "note": "f is never closed"}]
+	}`)
+	fixtures, err := eval.LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("Failed to load fixtures: %v", err)
+	}
+
+	var progress bytes.Buffer
+	for _, s := range eval.Run(downReviewer{}, fixtures, []eval.Version{{Name: "builtin"}}, &progress) {
+		if s.Errors != 1 {
+			t.Errorf("Expected an error for %s, got %+v", s.Agent, s)
+		}
+		if s.Agent == "bug-spotter" && (s.Expected != 1 || s.Recall() != 0) {
+			t.Errorf("Expected the leak counted as missed, got %+v", s)
+		}
+	}
+	if !strings.Contains(progress.String(), "bug-spotter    failed: connection refused") {
+		t.Errorf("Expected the failure in the progress, got %q", progress.String())
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/export"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+func TestSARIF_ArtifactLocations(t *testing.T) {
+	at := func(line int) protocol.Range {
+		return protocol.Range{Start: protocol.Position{Line: line}, End: protocol.Position{Line: line}}
+	}
+	var out bytes.Buffer
+	err := export.SARIF(&out, []findings.Finding{
+		{Agent: "bug-spotter", Path: "internal/server/tcp.go", Range: at(3), Severity: findings.SeverityError, Message: "leak"},
+		{Agent: "code-reviewer", Path: "/home/me/my project/main.go", Range: at(5), Severity: findings.SeverityInfo, Message: "naming"},
+	})
+	if err != nil {
+		t.Fatalf("SARIF failed: %v", err)
+	}
+
+	var log struct {
+		Runs []struct {
+			OriginalURIBaseIDs map[string]struct {
+				URI         string `json:"uri"`
+				Description struct {
+					Text string `json:"text"`
+				} `json:"description"`
+			} `json:"originalUriBaseIds"`
+			Results []struct {
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatalf("Invalid SARIF: %v", err)
+	}
+	run := log.Runs[0]
+
+	// Relative paths resolve against %SRCROOT%, which the run defines
+	if base, ok := run.OriginalURIBaseIDs["%SRCROOT%"]; !ok || base.Description.Text == "" {
+		t.Errorf("Expected %%SRCROOT%% defined, got %+v", run.OriginalURIBaseIDs)
+	}
+	if loc := run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation; loc.URI != "internal/server/tcp.go" || loc.URIBaseID != "%SRCROOT%" {
+		t.Errorf("Unexpected location for a relative path %+v", loc)
+	}
+	if loc := run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation; loc.URI != "file:///home/me/my%20project/main.go" || loc.URIBaseID != "" {
+		t.Errorf("Unexpected location for an absolute path %+v", loc)
+	}
+}
@@ -0,0 +1,231 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/history"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const historyRoot = "/work/project"
+
+// openHistory opens the history kept in dir, closing it when the test ends
+func openHistory(t *testing.T, dir string) *history.Store {
+	t.Helper()
+	h, err := history.Open(dir, historyRoot)
+	if err != nil {
+		t.Fatalf("Failed to open history: %v", err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+// historyLines returns the records in the one project file in dir
+func historyLines(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("Expected one project file, got %v: %v", paths, err)
+	}
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// saveFinding records a bug-spotter reply flagging line of e2eContent
+func saveFinding(t *testing.T, h *history.Store, line int, message string) []findings.Finding {
+	t.Helper()
+	text := fmt.Sprintf("[warning] L%d: %s", line, message)
+	fs := findings.Parse("bug-spotter", "main.go", text, e2eContent)
+	if len(fs) != 1 {
+		t.Fatalf("Expected one finding in %q, got %+v", text, fs)
+	}
+	if err := h.SaveResponse("bug-spotter", "main.go", text, fs); err != nil {
+		t.Fatalf("Failed to save response: %v", err)
+	}
+	return fs
+}
+
+// checkHistoryState checks the state holds the session and the one finding
+func checkHistoryState(t *testing.T, state history.State, sessionID string, want findings.Finding) {
+	t.Helper()
+	if state.SessionID != sessionID {
+		t.Errorf("Expected session %s, got %q", sessionID, state.SessionID)
+	}
+	if res := state.Responses["bug-spotter"]; res.Path != "main.go" || !strings.Contains(res.Text, want.Message) {
+		t.Errorf("Expected the latest response, got %+v", res)
+	}
+	got := state.Findings["main.go"]["bug-spotter"]
+	if len(got) != 1 || got[0] != want || got[0].Anchor() != want.Anchor() {
+		t.Errorf("Expected finding %+v anchored to %q, got %+v", want, want.Anchor(), got)
+	}
+}
+
+func TestHistory_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	h := openHistory(t, dir)
+
+	if err := h.SetSession("ses_first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SetSession("ses_first"); err != nil {
+		t.Fatal(err)
+	}
+	saveFinding(t, h, 5, "stale")
+	want := saveFinding(t, h, 6, "the error is ignored")[0]
+	if want.Anchor() == "" {
+		t.Fatal("Expected the finding anchored to its line")
+	}
+	checkHistoryState(t, h.State(), "ses_first", want)
+	h.Close()
+
+	// The project, one session and both replies
+	if lines := historyLines(t, dir); len(lines) != 4 {
+		t.Errorf("Expected 4 records, got %d:\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+
+	checkHistoryState(t, openHistory(t, dir).State(), "ses_first", want)
+}
+
+func TestHistory_CompactsLongFiles(t *testing.T) {
+	dir := t.TempDir()
+	h := openHistory(t, dir)
+
+	if err := h.SetSession("ses_old"); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 500 {
+		if err := h.SaveResponse("code-reviewer", "main.go", fmt.Sprintf("Reply %d", i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.SetSession("ses_new"); err != nil {
+		t.Fatal(err)
+	}
+	want := saveFinding(t, h, 6, "the error is ignored")[0]
+	h.Close()
+
+	// Reopening rewrites the file with the current state only
+	reopened := openHistory(t, dir)
+	checkHistoryState(t, reopened.State(), "ses_new", want)
+	if text := reopened.State().Responses["code-reviewer"].Text; text != "Reply 499" {
+		t.Errorf("Expected the latest code-reviewer reply, got %q", text)
+	}
+	// The project, the session, two replies and the findings
+	if lines := historyLines(t, dir); len(lines) != 5 {
+		t.Errorf("Expected 5 records after compaction, got %d", len(lines))
+	}
+
+	// Compacted files load the same and keep growing from there
+	if err := reopened.SetSession("ses_latest"); err != nil {
+		t.Fatal(err)
+	}
+	reopened.Close()
+	checkHistoryState(t, openHistory(t, dir).State(), "ses_latest", want)
+}
+
+func TestHistory_SkipsCorruptedLines(t *testing.T) {
+	dir := t.TempDir()
+	h := openHistory(t, dir)
+	if err := h.SetSession("ses_first"); err != nil {
+		t.Fatal(err)
+	}
+	h.Close()
+
+	// A record cut short by a crash, then one that isn't JSON at all
+	lines := historyLines(t, dir)
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	corrupted := append(lines, `{"type":"session","session_id":"ses_cut`, "not json", "")
+	if err := os.WriteFile(paths[0], []byte(strings.Join(corrupted, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h = openHistory(t, dir)
+	if id := h.State().SessionID; id != "ses_first" {
+		t.Errorf("Expected the intact session, got %q", id)
+	}
+	want := saveFinding(t, h, 6, "the error is ignored")[0]
+	h.Close()
+
+	checkHistoryState(t, openHistory(t, dir).State(), "ses_first", want)
+}
+
+func TestHistory_RestoresDashboard(t *testing.T) {
+	dir := t.TempDir()
+	h := openHistory(t, dir)
+	if err := h.SetSession("ses_saved123"); err != nil {
+		t.Fatal(err)
+	}
+	saveFinding(t, h, 6, "the error is ignored")
+	h.Close()
+
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetHistory(openHistory(t, dir))
+	var model tea.Model = m
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	// The client is reattached to the saved session and the reply is back
+	view := model.View()
+	if !strings.Contains(view, "Session: ses_save") {
+		t.Errorf("Expected the saved session reattached, got:\n%s", view)
+	}
+	if !strings.Contains(view, "the error is ignored") {
+		t.Errorf("Expected the saved reply restored, got:\n%s", view)
+	}
+}
+
+// runCmds runs cmd and the commands batched in it for their side effects,
+// dropping the messages they return
+func runCmds(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+	if batch, ok := cmd().(tea.BatchMsg); ok {
+		for _, c := range batch {
+			runCmds(c)
+		}
+	}
+}
+
+func TestHistory_SavesMovedFindings(t *testing.T) {
+	dir := t.TempDir()
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetHistory(openHistory(t, dir))
+	var model tea.Model = m
+
+	event := viewBufferEvent()
+	model, _ = model.Update(event)
+	model, cmd := model.Update(tui.AgentResponseMsg{
+		Agent:   "bug-spotter",
+		Path:    event.Path,
+		Content: e2eContent,
+		Text:    "[warning] L6: the error is ignored\n[info] L3: fmt is only used once",
+	})
+	runCmds(cmd)
+
+	// A line added at the top moves one finding, the import it pointed at
+	// goes with the other
+	event.Content = "// Package main greets\n" + strings.Replace(e2eContent, `import "fmt"`, `import "os"`, 1)
+	event.LineCount++
+	_, cmd = model.Update(event)
+	runCmds(cmd)
+
+	reopened, err := history.Open(dir, historyRoot)
+	if err != nil {
+		t.Fatalf("Failed to reopen history: %v", err)
+	}
+	defer reopened.Close()
+	got := reopened.State().Findings[event.Path]["bug-spotter"]
+	if len(got) != 1 || got[0].Range.Start.Line != 7 || got[0].Message != "the error is ignored" {
+		t.Errorf("Expected only the moved finding on line 7 after a restart, got %+v", got)
+	}
+}
@@ -0,0 +1,135 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/logging"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestLogging_RotatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "algopeeps.log")
+	f, err := logging.OpenFile(path, 64, 2)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer f.Close()
+
+	// 40 bytes a line: every other line rotates the file
+	for i := range 5 {
+		line := strings.Repeat(string(rune('a'+i)), 39) + "\n"
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	for file, want := range map[string]byte{"": 'e', ".1": 'd', ".2": 'c'} {
+		data, err := os.ReadFile(path + file)
+		if err != nil {
+			t.Fatalf("Expected %s: %v", path+file, err)
+		}
+		if len(data) != 40 || data[0] != want {
+			t.Errorf("Expected %s to hold the %c line, got %q", path+file, want, data)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 rotated files, got %s.3", path)
+	}
+}
+
+func TestLogging_TagsComponents(t *testing.T) {
+	// Loggers made before Setup log through it
+	server := logging.Component("server")
+
+	var out syncBuffer
+	logging.Setup(&out, slog.LevelInfo, nil)
+	t.Cleanup(func() { logging.Setup(discard{}, slog.LevelError+1, nil) })
+
+	server.Debug("too detailed")
+	server.Info("editor connected", "conn", 1)
+	logging.Component("opencode").With("session", "ses_1").Error("session lost")
+
+	log := out.String()
+	if strings.Contains(log, "too detailed") {
+		t.Error("Expected debug records left out at info level")
+	}
+	for _, want := range []string{
+		`level=INFO msg="editor connected" component=server conn=1`,
+		`level=ERROR msg="session lost" component=opencode session=ses_1`,
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("Expected %q in the log, got:\n%s", want, log)
+		}
+	}
+}
+
+func TestLogging_KeepsRecentWarnings(t *testing.T) {
+	recent := logging.NewRecent(2)
+	notified := make(chan logging.Entry, 8)
+	recent.Notify(func(e logging.Entry) { notified <- e })
+	log := slog.New(recent.Handler())
+
+	log.Info("not kept")
+	log.With(logging.ComponentKey, "server").Warn("ignoring malformed message", "conn", 1, "err", errors.New("invalid character 'g'"))
+	log.With(logging.ComponentKey, "opencode", "session", "ses_1").Error("session lost")
+	log.With(logging.ComponentKey, "tui").WithGroup("req").Warn("template failed", "event", "buffer_write")
+
+	// Only the last two warnings and errors are kept
+	entries := recent.Entries()
+	if len(entries) != 2 || recent.Total() != 3 || len(notified) != 3 {
+		t.Fatalf("Expected the last 2 of 3 entries, got %+v (total %d, notified %d)", entries, recent.Total(), len(notified))
+	}
+	if e := <-notified; e.Attrs != `conn=1 err="invalid character 'g'"` {
+		t.Errorf("Unexpected attributes %q", e.Attrs)
+	}
+	if e := entries[0]; e.Component != "opencode" || e.Level != slog.LevelError || e.Message != "session lost" || e.Attrs != "session=ses_1" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+	if e := entries[1]; e.Component != "tui" || e.Attrs != "req.event=buffer_write" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+}
+
+// discard is a log destination dropping everything
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+// TestView_LogPane snapshots the count of unseen warnings in the status bar
+// and the log pane showing them
+func TestView_LogPane(t *testing.T) {
+	recent := logging.NewRecent(100)
+	h := recent.Handler()
+	at := time.Date(2025, 1, 4, 12, 0, 0, 0, time.UTC)
+	logTo := func(component string, level slog.Level, msg string, args ...any) {
+		r := slog.NewRecord(at, level, msg, 0)
+		r.Add(args...)
+		at = at.Add(1500 * time.Millisecond)
+		if err := h.WithAttrs([]slog.Attr{slog.String(logging.ComponentKey, component)}).Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	m.SetLog(recent, "/state/algopeeps/algopeeps.log")
+	var model tea.Model = m
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	logTo("opencode", slog.LevelWarn, "disconnected", "err", "connection refused", "retry_in", "2s")
+	logTo("server", slog.LevelWarn, "ignoring malformed message", "conn", 2)
+	logTo("tui", slog.LevelError, "bug-spotter", "err", "context deadline exceeded")
+	model, _ = model.Update(tui.LogMsg{})
+	checkGolden(t, "view_log_unseen_120x40", model.View())
+
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("l")})
+	checkGolden(t, "view_log_open_120x40", model.View())
+}
@@ -0,0 +1,345 @@
+package integration
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/abhirupda/algopeeps/internal/backend"
+	"github.com/abhirupda/algopeeps/internal/lsp"
+)
+
+// rpcMessage is a JSON-RPC message as the editor sees it
+type rpcMessage struct {
+	ID     *int            `json:"id,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// lspClient plays the editor side of a language server running over pipes
+type lspClient struct {
+	t             *testing.T
+	w             io.WriteCloser
+	nextID        int
+	responses     chan rpcMessage
+	notifications chan rpcMessage
+	done          chan error // What Run returned
+}
+
+// startLSP runs a language server for b, reviewing edits once idle for
+// debounce
+func startLSP(t *testing.T, b backend.AgentBackend, debounce time.Duration) *lspClient {
+	t.Helper()
+
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &lspClient{
+		t:             t,
+		w:             clientOut,
+		responses:     make(chan rpcMessage, 64),
+		notifications: make(chan rpcMessage, 64),
+		done:          make(chan error, 1),
+	}
+
+	server := lsp.New(serverIn, serverOut, b, debounce)
+	go func() {
+		c.done <- server.Run()
+		serverIn.Close()
+		serverOut.Close()
+	}()
+	go c.readLoop(clientIn)
+	t.Cleanup(func() { clientOut.Close() })
+	return c
+}
+
+func (c *lspClient) readLoop(r io.Reader) {
+	reader := textproto.NewReader(bufio.NewReader(r))
+	for {
+		header, err := reader.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader.R, body); err != nil {
+			return
+		}
+		var msg rpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			return
+		}
+		if msg.Method != "" {
+			c.notifications <- msg
+		} else {
+			c.responses <- msg
+		}
+	}
+}
+
+// write sends raw as a framed message
+func (c *lspClient) write(raw []byte) {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(raw), raw); err != nil {
+		c.t.Fatalf("Failed to write to the server: %v", err)
+	}
+}
+
+func (c *lspClient) send(msg map[string]any) {
+	c.t.Helper()
+	msg["jsonrpc"] = "2.0"
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.write(raw)
+}
+
+// request sends a request and returns the response to it
+func (c *lspClient) request(method string, params any) rpcMessage {
+	c.t.Helper()
+	c.nextID++
+	c.send(map[string]any{"id": c.nextID, "method": method, "params": params})
+	select {
+	case res := <-c.responses:
+		if res.ID == nil || *res.ID != c.nextID {
+			c.t.Fatalf("Expected the response to request %d, got %+v", c.nextID, res)
+		}
+		return res
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("Timed out waiting for the response to %s", method)
+	}
+	return rpcMessage{}
+}
+
+func (c *lspClient) notify(method string, params any) {
+	c.t.Helper()
+	c.send(map[string]any{"method": method, "params": params})
+}
+
+// diagnostics returns the next diagnostics the server publishes
+func (c *lspClient) diagnostics() lsp.PublishDiagnosticsParams {
+	c.t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-c.notifications:
+			if msg.Method != "textDocument/publishDiagnostics" {
+				continue
+			}
+			var params lsp.PublishDiagnosticsParams
+			if err := json.Unmarshal(msg.Params, &params); err != nil {
+				c.t.Fatalf("Invalid diagnostics: %v", err)
+			}
+			return params
+		case <-timeout:
+			c.t.Fatal("Timed out waiting for diagnostics")
+		}
+	}
+}
+
+// wait returns what Run returned
+func (c *lspClient) wait() error {
+	c.t.Helper()
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("Timed out waiting for the server to stop")
+	}
+	return nil
+}
+
+const lspURI = "file:///lsp/project/main.go"
+
+func TestLSP_Initialize(t *testing.T) {
+	_, client := setupOpenCode(t)
+	c := startLSP(t, client, time.Hour)
+
+	res := c.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	var result lsp.InitializeResult
+	if err := json.Unmarshal(res.Result, &result); err != nil || res.Error != nil {
+		t.Fatalf("Unexpected initialize response %+v: %v", res, err)
+	}
+	sync := result.Capabilities.TextDocumentSync
+	if !sync.OpenClose || sync.Change != 1 || !sync.Save.IncludeText || !result.Capabilities.CodeActionProvider {
+		t.Errorf("Unexpected capabilities %+v", result.Capabilities)
+	}
+	if result.ServerInfo.Name != "algopeeps" {
+		t.Errorf("Unexpected server info %+v", result.ServerInfo)
+	}
+
+	// Unknown requests get an error, unknown notifications nothing
+	c.notify("$/setTrace", map[string]any{"value": "off"})
+	if res := c.request("workspace/symbol", map[string]any{}); res.Error == nil || res.Error.Code != -32601 {
+		t.Errorf("Expected method not found, got %+v", res)
+	}
+
+	if res := c.request("shutdown", nil); res.Error != nil || string(res.Result) != "null" {
+		t.Errorf("Unexpected shutdown response %+v", res)
+	}
+	c.notify("exit", nil)
+	if err := c.wait(); err != nil {
+		t.Errorf("Expected a clean exit, got %v", err)
+	}
+}
+
+func TestLSP_ExitBeforeShutdown(t *testing.T) {
+	_, client := setupOpenCode(t)
+	c := startLSP(t, client, time.Hour)
+
+	c.notify("exit", nil)
+	if err := c.wait(); err == nil {
+		t.Error("Expected exiting without shutdown to fail")
+	}
+}
+
+func TestLSP_ReviewsAndOffersFixes(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	fake.Reply("code-reviewer", "Small and readable, nothing to add.")
+	fake.Reply("bug-spotter", "[warning] L6: the error from fmt.Println is ignored\n"+
+		"```edit L6-L6\n\tif _, err := fmt.Println(\"hello\"); err != nil {\n\t\tpanic(err)\n\t}\n```")
+	c := startLSP(t, client, time.Hour)
+	c.request("initialize", map[string]any{})
+
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: lspURI, LanguageID: "go", Version: 1, Text: e2eContent},
+	})
+	diags := c.diagnostics()
+	if diags.URI != lspURI || len(diags.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic for %s, got %+v", lspURI, diags)
+	}
+	d := diags.Diagnostics[0]
+	if d.Range.Start.Line != 5 || d.Severity != lsp.SeverityWarning || d.Source != "bug-spotter" {
+		t.Errorf("Unexpected diagnostic %+v", d)
+	}
+	if prompts := fake.Prompts(); len(prompts) != 2 || !strings.Contains(prompts[0].Text, "fmt.Println(\"hello\")") {
+		t.Errorf("Expected the document in a prompt per agent, got %+v", prompts)
+	}
+
+	// The fix is offered on its line only
+	res := c.request("textDocument/codeAction", lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Range:        lsp.Range{Start: lsp.Position{Line: 5}, End: lsp.Position{Line: 5}},
+	})
+	var actions []lsp.CodeAction
+	if err := json.Unmarshal(res.Result, &actions); err != nil || len(actions) != 1 {
+		t.Fatalf("Expected one code action, got %s: %v", res.Result, err)
+	}
+	edit := actions[0].Edit.Changes[lspURI]
+	if actions[0].Title != "Apply fix from bug-spotter (L6-L6)" || len(edit) != 1 ||
+		edit[0].Range != (lsp.Range{Start: lsp.Position{Line: 5}, End: lsp.Position{Line: 6}}) ||
+		!strings.HasPrefix(edit[0].NewText, "\tif _, err := fmt.Println") {
+		t.Errorf("Unexpected code action %+v", actions[0])
+	}
+	res = c.request("textDocument/codeAction", lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Range:        lsp.Range{Start: lsp.Position{Line: 0}, End: lsp.Position{Line: 2}},
+	})
+	if string(res.Result) != "[]" {
+		t.Errorf("Expected no code actions away from the fix, got %s", res.Result)
+	}
+
+	// A line added above moves the diagnostic right away, long before the
+	// edit is reviewed, and drops the fix that no longer lines up
+	changed := strings.Replace(e2eContent, "func main", "// main greets\nfunc main", 1)
+	c.notify("textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.TextDocumentIdentifier{URI: lspURI},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: changed}},
+	})
+	diags = c.diagnostics()
+	if len(diags.Diagnostics) != 1 || diags.Diagnostics[0].Range.Start.Line != 6 {
+		t.Errorf("Expected the diagnostic to move to line 6, got %+v", diags.Diagnostics)
+	}
+	res = c.request("textDocument/codeAction", lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Range:        lsp.Range{Start: lsp.Position{Line: 0}, End: lsp.Position{Line: 10}},
+	})
+	if string(res.Result) != "[]" {
+		t.Errorf("Expected the fix dropped after the change, got %s", res.Result)
+	}
+}
+
+func TestLSP_MalformedMessages(t *testing.T) {
+	_, client := setupOpenCode(t)
+	c := startLSP(t, client, time.Hour)
+
+	// Bad params of a request are reported, of a notification ignored
+	c.notify("textDocument/didOpen", "not an object")
+	c.nextID++
+	c.send(map[string]any{"id": c.nextID, "method": "textDocument/codeAction", "params": []int{1}})
+	if res := <-c.responses; res.Error == nil || res.Error.Code != -32602 {
+		t.Errorf("Expected invalid params, got %+v", res)
+	}
+
+	// A header the framing can't be recovered from ends the session
+	if _, err := io.WriteString(c.w, "Content-Length: twelve\r\n\r\n{}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.wait(); err == nil || !strings.Contains(err.Error(), "invalid Content-Length") {
+		t.Errorf("Expected the malformed header to be reported, got %v", err)
+	}
+}
+
+func TestLSP_PositionsCountUTF16(t *testing.T) {
+	content := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"héllo 🙂\")\n}\n// ünïcödé 🙂"
+	flagged := "\tfmt.Println(\"héllo 🙂\")"
+	last := "// ünïcödé 🙂"
+
+	fake, client := setupOpenCode(t)
+	fake.Reply("code-reviewer", "Nothing to add.")
+	fake.Reply("bug-spotter", "[warning] L6: the error is ignored\n"+
+		"```edit L8-L8\n// unicode\n```\n"+
+		"```edit L40-L41\n// past the end\n```")
+	c := startLSP(t, client, time.Hour)
+	c.request("initialize", map[string]any{})
+	c.notify("textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+		TextDocument: lsp.TextDocumentItem{URI: lspURI, LanguageID: "go", Version: 1, Text: content},
+	})
+
+	// The diagnostic spans the line after its indentation, in UTF-16 units
+	diags := c.diagnostics()
+	if len(diags.Diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %+v", diags)
+	}
+	want := lsp.Range{
+		Start: lsp.Position{Line: 5, Character: 1},
+		End:   lsp.Position{Line: 5, Character: len(utf16.Encode([]rune(flagged)))},
+	}
+	if got := diags.Diagnostics[0].Range; got != want {
+		t.Errorf("Expected range %+v, got %+v", want, got)
+	}
+
+	// The fix of the last line ends at its end in UTF-16 units; the one past
+	// the end of the document isn't offered
+	res := c.request("textDocument/codeAction", lsp.CodeActionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: lspURI},
+		Range:        lsp.Range{Start: lsp.Position{Line: 0}, End: lsp.Position{Line: 100}},
+	})
+	var actions []lsp.CodeAction
+	if err := json.Unmarshal(res.Result, &actions); err != nil || len(actions) != 1 {
+		t.Fatalf("Expected one code action, got %s: %v", res.Result, err)
+	}
+	edit := actions[0].Edit.Changes[lspURI]
+	want = lsp.Range{
+		Start: lsp.Position{Line: 7},
+		End:   lsp.Position{Line: 7, Character: len(utf16.Encode([]rune(last)))},
+	}
+	if len(edit) != 1 || edit[0].Range != want || edit[0].NewText != "// unicode" {
+		t.Errorf("Expected the last line replaced over %+v, got %+v", want, edit)
+	}
+}
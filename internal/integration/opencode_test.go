@@ -0,0 +1,364 @@
+package integration
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/opencode/opencodetest"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// These tests run against the in-process fake OpenCode server, so they need
+// neither INTEGRATION_TESTS nor opencode serve
+
+// recorder is a Bubble Tea model handing every message it gets to a channel
+type recorder struct {
+	msgs chan tea.Msg
+}
+
+func (r recorder) Init() tea.Cmd { return nil }
+
+func (r recorder) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	r.msgs <- msg
+	return r, nil
+}
+
+func (r recorder) View() string { return "" }
+
+// setupOpenCode starts a fake OpenCode server and a client talking to it
+func setupOpenCode(t *testing.T) (*opencodetest.Server, *opencode.Client) {
+	t.Helper()
+
+	fake := opencodetest.NewServer()
+	t.Cleanup(fake.Close)
+
+	client, err := opencode.NewClient(opencode.Config{BaseURL: fake.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return fake, client
+}
+
+// subscribe runs the client lifecycle, returning the messages it sends
+func subscribe(t *testing.T, client *opencode.Client) <-chan tea.Msg {
+	t.Helper()
+
+	msgs := make(chan tea.Msg, 256)
+	p := tea.NewProgram(recorder{msgs: msgs}, tea.WithInput(nil), tea.WithOutput(io.Discard), tea.WithoutRenderer())
+	go func() { _, _ = p.Run() }()
+	t.Cleanup(p.Kill)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() { _ = client.SubscribeEvents(ctx, p) }()
+	return msgs
+}
+
+// waitForState waits until the lifecycle reaches state, failing the test
+// after timeout
+func waitForState(t *testing.T, msgs <-chan tea.Msg, state opencode.State) opencode.StateMsg {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-msgs:
+			if s, ok := msg.(opencode.StateMsg); ok && s.State == state {
+				return s
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for state %s", state)
+		}
+	}
+}
+
+// collectText gathers the agent text sent until every agent in agents went
+// idle
+func collectText(t *testing.T, msgs <-chan tea.Msg, agents ...string) map[string]string {
+	t.Helper()
+
+	text := make(map[string]string)
+	idle := make(map[string]bool)
+	timeout := time.After(5 * time.Second)
+	for len(idle) < len(agents) {
+		select {
+		case msg := <-msgs:
+			switch msg := msg.(type) {
+			case opencode.AgentTextMsg:
+				text[msg.Agent] += msg.Text
+			case opencode.AgentIdleMsg:
+				idle[msg.Agent] = true
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for agents to go idle, got %q", text)
+		}
+	}
+	return text
+}
+
+func TestOpenCodeClient_Prompt(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	fake.Reply("bug-spotter", "[warning] L3: err is ignored")
+
+	if err := client.EnsureSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if got := fake.SessionIDs(); len(got) != 1 || got[0] != client.SessionID() {
+		t.Fatalf("Expected session %s on the server, got %v", client.SessionID(), got)
+	}
+
+	text, err := client.Prompt("bug-spotter", "review this")
+	if err != nil {
+		t.Fatalf("Prompt failed: %v", err)
+	}
+	if text != "[warning] L3: err is ignored" {
+		t.Errorf("Expected the scripted reply, got %q", text)
+	}
+
+	prompts := fake.Prompts()
+	if len(prompts) != 1 || prompts[0].Agent != "bug-spotter" || prompts[0].Text != "review this" {
+		t.Errorf("Expected one prompt to bug-spotter, got %+v", prompts)
+	}
+	if usage := client.Usage("bug-spotter"); usage.InputTokens != 2 || usage.OutputTokens != 5 {
+		t.Errorf("Expected usage of 2 input and 5 output tokens, got %+v", usage)
+	}
+}
+
+func TestOpenCodeClient_RecreatesDeletedSession(t *testing.T) {
+	fake, client := setupOpenCode(t)
+
+	if err := client.EnsureSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	first := client.SessionID()
+	fake.RemoveSession(first)
+
+	if err := client.EnsureSession(); err != nil {
+		t.Fatalf("Failed to recreate session: %v", err)
+	}
+	if client.SessionID() == first || client.SessionID() == "" {
+		t.Errorf("Expected a new session replacing %s, got %q", first, client.SessionID())
+	}
+}
+
+func TestOpenCodeClient_StreamsAgentText(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	fake.Reply("code-reviewer", "Consider naming this loop variable.")
+
+	msgs := subscribe(t, client)
+	waitForState(t, msgs, opencode.StateConnecting)
+	ready := waitForState(t, msgs, opencode.StateSessionReady)
+	if ready.SessionID == "" {
+		t.Error("Expected the session ready state to carry the session")
+	}
+	waitForState(t, msgs, opencode.StateStreaming)
+
+	if _, err := client.Prompt("code-reviewer", "review this"); err != nil {
+		t.Fatalf("Prompt failed: %v", err)
+	}
+	text := collectText(t, msgs, "code-reviewer", "bug-spotter")
+	if text["code-reviewer"] != "Consider naming this loop variable." {
+		t.Errorf("Expected the reply streamed as agent text, got %q", text["code-reviewer"])
+	}
+}
+
+func TestOpenCodeClient_RecoversTextMissedWhileDisconnected(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	fake.Reply("bug-spotter", "Missed while the stream was down.")
+
+	msgs := subscribe(t, client)
+	waitForState(t, msgs, opencode.StateStreaming)
+
+	fake.RefuseStreams(true)
+	fake.DropStreams()
+	down := waitForState(t, msgs, opencode.StateDisconnected)
+	if down.Err == nil {
+		t.Error("Expected the disconnected state to carry why")
+	}
+
+	if _, err := client.Prompt("bug-spotter", "review this"); err != nil {
+		t.Fatalf("Prompt failed: %v", err)
+	}
+	fake.RefuseStreams(false)
+
+	text := collectText(t, msgs, "bug-spotter")
+	if text["bug-spotter"] != "Missed while the stream was down." {
+		t.Errorf("Expected the missed reply to be recovered, got %q", text["bug-spotter"])
+	}
+}
+
+func TestOpenCodeClient_FollowsReattachedSession(t *testing.T) {
+	fake, client := setupOpenCode(t)
+
+	msgs := subscribe(t, client)
+	waitForState(t, msgs, opencode.StateStreaming)
+	first := client.SessionID()
+
+	// A second client makes the session to switch to
+	other, err := opencode.NewClient(opencode.Config{BaseURL: fake.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer other.Close()
+	if err := other.EnsureSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	client.Reattach(other.SessionID())
+	ready := waitForState(t, msgs, opencode.StateSessionReady)
+	if ready.SessionID != other.SessionID() || ready.SessionID == first {
+		t.Errorf("Expected the lifecycle to move to session %s, got %s", other.SessionID(), ready.SessionID)
+	}
+	waitForState(t, msgs, opencode.StateStreaming)
+}
+
+func TestCouncilReview_ThroughOpenCode(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	fake.ReplyFunc(func(agent, prompt string) string {
+		if agent == "bug-spotter" && strings.Contains(prompt, "os.Open") {
+			return "[error] L4: the file is never closed"
+		}
+		return opencodetest.DefaultReply
+	})
+
+	if err := client.EnsureSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	content := "package main\n\nfunc main() {\n\tf, _ := os.Open(\"x\")\n\t_ = f\n}\n"
+	req := council.Request{
+		Path:     "main.go",
+		Filename: "main.go",
+		Filetype: "go",
+		Event:    "BufWritePost",
+		Content:  content,
+	}
+	responses := council.Review(client, req)
+	if len(responses) != len(council.Agents) {
+		t.Fatalf("Expected a response per agent, got %d", len(responses))
+	}
+
+	var all []findings.Finding
+	for _, res := range responses {
+		if res.Err != nil {
+			t.Fatalf("%s failed: %v", res.Agent, res.Err)
+		}
+		all = append(all, findings.Parse(res.Agent, req.Path, res.Text, content)...)
+	}
+	if len(all) != 1 {
+		t.Fatalf("Expected one finding, got %+v", all)
+	}
+	if f := all[0]; f.Agent != "bug-spotter" || f.Severity != findings.SeverityError || f.Range.Start.Line != 4 {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+	if got := len(fake.Prompts()); got != len(council.Agents) {
+		t.Errorf("Expected %d prompts, got %d", len(council.Agents), got)
+	}
+}
+
+// runBatch runs cmd and every command batched in it, feeding their messages
+// back to model
+func runBatch(model tea.Model, cmd tea.Cmd) tea.Model {
+	if cmd == nil {
+		return model
+	}
+	msg := cmd()
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, c := range batch {
+			model = runBatch(model, c)
+		}
+		return model
+	}
+	model, _ = model.Update(msg)
+	return model
+}
+
+func TestSessionBrowser_CountsEveryListedSession(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	for range 3 {
+		if err := client.NewSession(); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		if _, err := client.Prompt("bug-spotter", "look"); err != nil {
+			t.Fatalf("Prompt failed: %v", err)
+		}
+	}
+
+	m := tui.NewModel(opencode.Config{BaseURL: fake.URL})
+	var model tea.Model = m
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model, cmd := model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	model, cmd = model.Update(cmd())
+
+	// The list shows before any session is counted
+	if got := fake.MessageRequests(); len(got) != 0 {
+		t.Errorf("Expected listing to fetch no messages, got %v", got)
+	}
+	if view := model.View(); strings.Contains(view, "msgs") {
+		t.Errorf("Expected the list without counts, got:\n%s", view)
+	}
+
+	model = runBatch(model, cmd)
+	if got := fake.MessageRequests(); len(got) != 3 {
+		t.Errorf("Expected every session counted once, got %v", got)
+	}
+	if view := model.View(); strings.Count(view, "2 msgs") != 3 {
+		t.Errorf("Expected a count on every row, got:\n%s", view)
+	}
+}
+
+func TestSessionBrowser_RenameKeepsProject(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	client.SetProject("algopeeps")
+	if err := client.NewSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	before, err := client.ListSessions()
+	if err != nil || len(before) != 1 || before[0].Project != "algopeeps" {
+		t.Fatalf("Expected one session of algopeeps, got %+v: %v", before, err)
+	}
+
+	// Rename from the browser to a title that looks like a project and date
+	m := tui.NewModel(opencode.Config{BaseURL: fake.URL})
+	var model tea.Model = m
+	model, _ = model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model = run(model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")}))
+	model, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	for range before[0].Title {
+		model, _ = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	for _, key := range []tea.KeyMsg{
+		{Type: tea.KeyRunes, Runes: []rune("parser")},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyRunes, Runes: []rune("-")},
+		{Type: tea.KeySpace},
+		{Type: tea.KeyRunes, Runes: []rune("2026-01-02")},
+	} {
+		model, _ = model.Update(key)
+	}
+	run(model.Update(tea.KeyMsg{Type: tea.KeyEnter}))
+
+	after, err := client.ListSessions()
+	if err != nil || len(after) != 1 {
+		t.Fatalf("Expected the session still listed, got %+v: %v", after, err)
+	}
+	if after[0].Title != "parser - 2026-01-02" || after[0].Project != "algopeeps" {
+		t.Errorf("Expected the new title and the project kept, got %+v", after[0])
+	}
+
+	// Sessions without a project take any title as is
+	if err := client.RenameSession(after[0].ID, "", "a - b"); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := client.ListSessions(); len(after) != 1 || after[0].Title != "a - b" || after[0].Project != "/" {
+		t.Errorf("Expected the title as given and the directory as project, got %+v", after)
+	}
+}
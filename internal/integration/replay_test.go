@@ -0,0 +1,163 @@
+package integration
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+	"github.com/abhirupda/algopeeps/internal/replay"
+	"github.com/abhirupda/algopeeps/internal/server"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the server while the test
+// reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// recordSession sends msgs to a recording TCP server, one connection each,
+// and returns the recording
+func recordSession(t *testing.T, msgs ...any) []replay.Entry {
+	t.Helper()
+
+	var recording syncBuffer
+	tcpServer := server.New("127.0.0.1:0")
+	tcpServer.SetRecorder(replay.NewRecorder(&recording))
+	if err := tcpServer.Start(); err != nil {
+		t.Fatalf("Failed to start TCP server: %v", err)
+	}
+	t.Cleanup(func() { _ = tcpServer.Stop() })
+
+	for i, msg := range msgs {
+		conn, err := dialTCP(tcpServer.Addr())
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		send(t, conn, msg)
+		// Not JSON: left out of the recording
+		if _, err := conn.Write([]byte("garbage\n")); err != nil {
+			t.Fatalf("Failed to send: %v", err)
+		}
+		conn.Close()
+
+		// Connections are read concurrently; keep them in order
+		deadline := time.Now().Add(5 * time.Second)
+		for strings.Count(recording.String(), "\n") <= i {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for the recording, got %q", recording.String())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	entries, err := replay.Read(strings.NewReader(recording.String()))
+	if err != nil {
+		t.Fatalf("Failed to read the recording: %v", err)
+	}
+	return entries
+}
+
+func TestReplay_RecordsEditorLines(t *testing.T) {
+	entries := recordSession(t,
+		protocol.BufferEvent{Type: protocol.MessageBufferUpdate, Event: protocol.EventBufferWrite, Buffer: e2eBuffer()},
+		protocol.QuestionMessage{Type: protocol.MessageQuestion, ID: "q-1", Question: "Why?", Buffer: e2eBuffer()},
+	)
+
+	if len(entries) != 2 {
+		t.Fatalf("Expected two entries, got %d", len(entries))
+	}
+	if entries[0].Conn != 1 || entries[1].Conn != 2 {
+		t.Errorf("Expected connections 1 and 2, got %d and %d", entries[0].Conn, entries[1].Conn)
+	}
+	if entries[1].Time.Before(entries[0].Time) {
+		t.Error("Expected entries in the order they arrived")
+	}
+	if !strings.Contains(string(entries[1].Line), `"question":"Why?"`) {
+		t.Errorf("Expected the line as sent, got %s", entries[1].Line)
+	}
+}
+
+func TestReplay_RunsRecordingThroughCouncil(t *testing.T) {
+	fake, client := setupOpenCode(t)
+	fake.ReplyFunc(func(agent, prompt string) string {
+		if agent == "bug-spotter" && strings.Contains(prompt, "fmt.Println") {
+			return "[warning] L6: the error from fmt.Println is ignored"
+		}
+		return "Println only fails if stdout is closed."
+	})
+	if err := client.EnsureSession(); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	entries := recordSession(t,
+		protocol.BufferEvent{Type: protocol.MessageBufferUpdate, Event: protocol.EventBufferWrite, Buffer: e2eBuffer()},
+		protocol.QuestionMessage{Type: protocol.MessageQuestion, ID: "q-1", Agent: "code-reviewer", Question: "Can Println fail here?", Buffer: e2eBuffer()},
+	)
+
+	var out bytes.Buffer
+	result, err := replay.Run(client, entries, 0, &out)
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if result.Events != 1 || result.Questions != 1 || len(result.Errors) != 0 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if len(result.Findings) != 1 {
+		t.Fatalf("Expected one finding, got %+v", result.Findings)
+	}
+	if f := result.Findings[0]; f.Agent != "bug-spotter" || f.Severity != findings.SeverityWarning || f.Range.Start.Line != 6 {
+		t.Errorf("Unexpected finding %+v", f)
+	}
+
+	// A review per agent, then the question to code-reviewer only
+	prompts := fake.Prompts()
+	if len(prompts) != 3 || prompts[2].Agent != "code-reviewer" || !strings.Contains(prompts[2].Text, "Can Println fail here?") {
+		t.Errorf("Unexpected prompts %+v", prompts)
+	}
+	if !strings.Contains(out.String(), "Println only fails if stdout is closed.") {
+		t.Errorf("Expected the answer in the output, got %q", out.String())
+	}
+}
+
+func TestReplay_PlaysAtRecordedPace(t *testing.T) {
+	start := time.Date(2025, 1, 4, 12, 0, 0, 0, time.UTC)
+	entries := []replay.Entry{
+		{Time: start},
+		{Time: start.Add(400 * time.Millisecond)},
+	}
+
+	var gap time.Duration
+	var first time.Time
+	err := replay.Play(entries, 4, func(e replay.Entry) error {
+		if first.IsZero() {
+			first = time.Now()
+		} else {
+			gap = time.Since(first)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	// 400ms at 4x
+	if gap < 90*time.Millisecond || gap > 300*time.Millisecond {
+		t.Errorf("Expected about 100ms between entries, got %s", gap)
+	}
+}
@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/review"
+)
+
+func TestParseDiff(t *testing.T) {
+	// hunk is what a test expects of a review.Hunk, its text aside
+	type hunk struct {
+		path                     string
+		start, lines             int
+		changedStart, changedEnd int
+	}
+
+	tests := map[string]struct {
+		diff string
+		want []hunk
+	}{
+		"counts left out": {
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -3 +3 @@ func a() {\n-\treturn 1\n+\treturn 2\n",
+			want: []hunk{{"a.go", 3, 1, 3, 3}},
+		},
+		"hunks of several files": {
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1,3 +1,4 @@\n a\n+b\n c\n d\n@@ -10,2 +11,2 @@\n x\n-y\n+z\n" +
+				"diff --git a/b.go b/b.go\nnew file mode 100644\n--- /dev/null\n+++ b/b.go\n@@ -0,0 +1,2 @@\n+package b\n+\n",
+			want: []hunk{{"a.go", 1, 4, 2, 2}, {"a.go", 11, 2, 12, 12}, {"b.go", 1, 2, 1, 2}},
+		},
+		"only removals": {
+			diff: "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -4,2 +3,0 @@\n-x\n-y\n",
+			want: []hunk{{"a.go", 3, 0, 4, 4}},
+		},
+		"added line that looks like a header": {
+			diff: "diff --git a/a.md b/a.md\n--- a/a.md\n+++ b/a.md\n@@ -1,0 +2,1 @@\n+++ not a path\n",
+			want: []hunk{{"a.md", 2, 1, 2, 2}},
+		},
+		"deleted file": {
+			diff: "diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n@@ -1,2 +0,0 @@\n-package gone\n-\n",
+		},
+		"renamed file": {
+			diff: "diff --git a/old.go b/new.go\nsimilarity index 90%\nrename from old.go\nrename to new.go\n--- a/old.go\n+++ b/new.go\n@@ -1,3 +1,3 @@\n a\n-b\n+c\n d\n",
+			want: []hunk{{"new.go", 1, 3, 2, 2}},
+		},
+		"renamed file, content unchanged": {
+			diff: "diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go\n",
+		},
+		"binary file": {
+			diff: "diff --git a/logo.png b/logo.png\nindex 1111111..2222222 100644\nBinary files a/logo.png and b/logo.png differ\n" +
+				"diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-a\n+b\n",
+			want: []hunk{{"a.go", 1, 1, 1, 1}},
+		},
+		"empty": {},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := review.ParseDiff(tt.diff)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %d hunks, got %+v", len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				h := got[i]
+				if h.Path != want.path || h.NewStart != want.start || h.NewLines != want.lines ||
+					h.Changed.Start.Line != want.changedStart || h.Changed.End.Line != want.changedEnd {
+					t.Errorf("Hunk %d: expected %+v, got %+v", i, want, h)
+				}
+			}
+		})
+	}
+}
+
+func TestScan_SessionPerJob(t *testing.T) {
+	fake, first := setupOpenCode(t)
+	fake.Reply("bug-spotter", "[warning] L3: x is never used")
+	second, err := opencode.NewClient(opencode.Config{BaseURL: fake.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer second.Close()
+	for _, client := range []*opencode.Client{first, second} {
+		if err := client.EnsureSession(); err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	var files []string
+	for i := range 6 {
+		path := filepath.Join(dir, fmt.Sprintf("f%d.go", i))
+		writeFile(t, path, "package scan\n\nvar x = 1\n")
+		files = append(files, path)
+	}
+
+	result, err := review.Scan([]council.Prompter{first, second}, files, nil)
+	if err != nil || len(result.Errors) != 0 {
+		t.Fatalf("Scan failed: %v %v", err, result.Errors)
+	}
+	if result.Units != 6 || len(result.Findings) != 6 {
+		t.Errorf("Expected a finding in each of 6 chunks, got %d in %d", len(result.Findings), result.Units)
+	}
+
+	// Both agents review a chunk in the session of the job that took it
+	sessions := make(map[string]string) // By file
+	for _, p := range fake.Prompts() {
+		if p.SessionID != first.SessionID() && p.SessionID != second.SessionID() {
+			t.Fatalf("Prompt sent to unknown session %s", p.SessionID)
+		}
+		for _, path := range files {
+			if !strings.Contains(p.Text, filepath.Base(path)) {
+				continue
+			}
+			if s, ok := sessions[path]; ok && s != p.SessionID {
+				t.Errorf("Expected both prompts for %s in one session", path)
+			}
+			sessions[path] = p.SessionID
+		}
+	}
+	if len(sessions) != len(files) {
+		t.Errorf("Expected prompts for every file, got %v", sessions)
+	}
+}
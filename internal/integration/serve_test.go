@@ -0,0 +1,144 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/opencode/opencodetest"
+)
+
+// stubServeEnv makes the test binary act as opencode serve, see stubServe
+const stubServeEnv = "ALGOPEEPS_STUB_OPENCODE_SERVE"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(stubServeEnv); mode != "" {
+		stubServe(mode)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// stubServe serves the fake OpenCode API on the port it is given the way
+// opencode serve is. It exits at once in "fail" mode and otherwise when
+// asked to at /crash.
+func stubServe(mode string) {
+	if mode == "fail" {
+		fmt.Println("stub: failing")
+		os.Exit(2)
+	}
+	port := ""
+	for i, arg := range os.Args {
+		if arg == "--port" && i+1 < len(os.Args) {
+			port = os.Args[i+1]
+		}
+	}
+
+	fake := opencodetest.NewServer()
+	mux := http.NewServeMux()
+	mux.Handle("/", fake.Handler())
+	mux.HandleFunc("/crash", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Println("stub: crashing")
+		os.Exit(1)
+	})
+	fmt.Println("stub: serving on", port)
+	fmt.Println(http.ListenAndServe("127.0.0.1:"+port, mux))
+	os.Exit(3)
+}
+
+// startStubServer starts the test binary as opencode serve in mode
+func startStubServer(t *testing.T, mode string, log *syncBuffer) (*opencode.Server, error) {
+	t.Helper()
+	t.Setenv(stubServeEnv, mode)
+	return opencode.StartServer(opencode.ServerConfig{
+		Command:      os.Args[0],
+		Log:          log,
+		ReadyTimeout: 10 * time.Second,
+	})
+}
+
+// waitForLog waits until the server output has n lines containing s
+func waitForLog(t *testing.T, log *syncBuffer, s string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for strings.Count(log.String(), s) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d lines with %q in:\n%s", n, s, log.String())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+var restartDelayRe = regexp.MustCompile(`restarting in (\S+)`)
+
+func TestStartServer_RestartsWithBackoff(t *testing.T) {
+	log := &syncBuffer{}
+	srv, err := startStubServer(t, "serve", log)
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop()
+	if err := opencode.Probe(context.Background(), srv.URL()); err != nil {
+		t.Fatalf("Expected the server to answer once started: %v", err)
+	}
+
+	// Crash it twice in a row; each restart waits longer than the last and
+	// comes back on the same port
+	for i := 1; i <= 2; i++ {
+		if _, err := http.Get(srv.URL() + "/crash"); err == nil {
+			t.Fatal("Expected the crash to cut the request short")
+		}
+		waitForLog(t, log, "stub: serving on", i+1)
+		if err := opencode.Probe(context.Background(), srv.URL()); err != nil {
+			t.Fatalf("Expected the server to answer after restart %d: %v", i, err)
+		}
+	}
+
+	var delays []time.Duration
+	for _, match := range restartDelayRe.FindAllStringSubmatch(log.String(), -1) {
+		d, err := time.ParseDuration(match[1])
+		if err != nil {
+			t.Fatalf("Invalid delay in %q: %v", match[0], err)
+		}
+		delays = append(delays, d)
+	}
+	if len(delays) != 2 || delays[0] < 250*time.Millisecond || delays[1] < delays[0] {
+		t.Errorf("Expected two growing restart delays, got %v in:\n%s", delays, log.String())
+	}
+
+	// Stopping isn't a crash
+	srv.Stop()
+	if err := opencode.Probe(context.Background(), srv.URL()); err == nil {
+		t.Error("Expected the server to be down after Stop")
+	}
+	if n := strings.Count(log.String(), "restarting in"); n != 2 {
+		t.Errorf("Expected no restart after Stop, got %d restarts", n)
+	}
+}
+
+func TestStartServer_ExitsBeforeReady(t *testing.T) {
+	log := &syncBuffer{}
+	_, err := startStubServer(t, "fail", log)
+	if err == nil || !strings.Contains(err.Error(), "exited before it was ready") {
+		t.Fatalf("Expected the early exit reported, got %v", err)
+	}
+	if !strings.Contains(log.String(), "stub: failing") {
+		t.Errorf("Expected the server output in the log, got %q", log.String())
+	}
+}
+
+func TestEnsureServer_UsesRunningServer(t *testing.T) {
+	fake, _ := setupOpenCode(t)
+	t.Setenv(stubServeEnv, "fail")
+
+	url, srv, err := opencode.EnsureServer(fake.URL, opencode.ServerConfig{Command: os.Args[0]})
+	if err != nil || url != fake.URL || srv != nil {
+		t.Errorf("Expected the running server at %s, got %s, %v, %v", fake.URL, url, srv, err)
+	}
+}
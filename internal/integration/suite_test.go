@@ -1,14 +1,51 @@
 package integration
 
 import (
+	"flag"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/abhirupda/algopeeps/internal/server"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+func init() {
+	// Snapshots are compared as plain text, whatever the terminal running
+	// the tests supports
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
+
+// checkGolden compares got with testdata/golden/name.golden, or rewrites the
+// file when the tests run with -update
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("Output differs from %s (run with -update to accept it)\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
 // skipIfNotIntegration skips the test if INTEGRATION_TESTS env var is not set
 func skipIfNotIntegration(t *testing.T) {
 	t.Helper()
@@ -112,14 +112,6 @@ func TestTCPServer_ParsesBufferEvent(t *testing.T) {
 		event.Buffer.Name, event.Type, event.Event)
 }
 
-func TestOpenCodeIntegration_Stub(t *testing.T) {
-	skipIfNotIntegration(t)
-
-	// This is a stub test for OpenCode integration
-	// OpenCode tests require a live server running separately
-	t.Skip("OpenCode integration tests require live server - not yet implemented")
-}
-
 // TestBufferEventValidation tests that buffer events validate correctly
 func TestBufferEventValidation(t *testing.T) {
 	skipIfNotIntegration(t)
@@ -252,6 +244,67 @@ func TestInvalidJSON(t *testing.T) {
 	t.Log("Server gracefully handled invalid JSON and continued processing")
 }
 
+// TestBroadcastEdit tests that edit proposals reach connected editors
+func TestBroadcastEdit(t *testing.T) {
+	skipIfNotIntegration(t)
+
+	srv, addr := setupTestServer(t)
+	if srv == nil {
+		t.Fatal("Server setup failed")
+	}
+
+	conn, err := dialTCP(addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	msg := protocol.EditMessage{
+		Type: protocol.MessageEdit,
+		Edit: protocol.EditProposal{
+			Path:  "/path/to/main.go",
+			Agent: "bug-spotter",
+			Text:  "\tif err != nil {\n\t\treturn err\n\t}",
+			Range: protocol.Range{
+				Start: protocol.Position{Line: 3},
+				End:   protocol.Position{Line: 4},
+			},
+		},
+	}
+
+	// The server registers the client asynchronously
+	deadline := time.Now().Add(time.Second)
+	for {
+		err = srv.Broadcast(msg)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Failed to broadcast edit: %v", err)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("Failed to read edit message: %v", err)
+	}
+
+	var got protocol.EditMessage
+	if err := json.Unmarshal(line, &got); err != nil {
+		t.Fatalf("Edit message is not valid JSON: %v", err)
+	}
+
+	if got.Type != protocol.MessageEdit {
+		t.Errorf("Expected message type %s, got %s", protocol.MessageEdit, got.Type)
+	}
+
+	if got.Edit != msg.Edit {
+		t.Errorf("Expected edit %+v, got %+v", msg.Edit, got.Edit)
+	}
+}
+
 // BenchmarkBufferEventParsing benchmarks JSON parsing of buffer events
 func BenchmarkBufferEventParsing(b *testing.B) {
 	if os.Getenv("INTEGRATION_TESTS") != "1" {
@@ -0,0 +1,141 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/tui"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// viewSizes are the terminal sizes dashboard snapshots are taken at: below
+// the 40 column fallback, a classic terminal, and two roomy ones
+var viewSizes = []struct{ width, height int }{
+	{30, 15},
+	{80, 24},
+	{120, 40},
+	{200, 50},
+}
+
+// viewStates are the dashboard states snapshots are taken of, each built by
+// feeding the model the messages that lead to it
+var viewStates = []struct {
+	name string
+	msgs []tea.Msg
+}{
+	{
+		name: "startup",
+	},
+	{
+		name: "connecting",
+		msgs: []tea.Msg{
+			tui.ConnectionStatusMsg{Source: "nvim", Connected: true},
+			opencode.StateMsg{State: opencode.StateConnecting},
+		},
+	},
+	{
+		name: "thinking",
+		msgs: []tea.Msg{
+			tui.ConnectionStatusMsg{Source: "nvim", Connected: true},
+			opencode.StateMsg{State: opencode.StateStreaming},
+			viewBufferEvent(),
+			opencode.AgentTextMsg{Agent: "code-reviewer", Text: "The loop reads "},
+		},
+	},
+	{
+		name: "replied",
+		msgs: []tea.Msg{
+			tui.ConnectionStatusMsg{Source: "nvim", Connected: true},
+			opencode.StateMsg{State: opencode.StateStreaming},
+			viewBufferEvent(),
+			tui.AgentResponseMsg{
+				Agent:   "code-reviewer",
+				Path:    "/view/project/main.go",
+				Text:    "The loop reads well. Consider naming the counter after what it counts.",
+				Content: e2eContent,
+			},
+			tui.AgentResponseMsg{
+				Agent:   "bug-spotter",
+				Path:    "/view/project/main.go",
+				Text:    "[warning] L6: the error from fmt.Println is ignored",
+				Content: e2eContent,
+			},
+		},
+	},
+	{
+		name: "long",
+		msgs: []tea.Msg{
+			tui.ConnectionStatusMsg{Source: "nvim", Connected: true},
+			opencode.StateMsg{State: opencode.StateStreaming},
+			viewBufferEvent(),
+			tui.AgentResponseMsg{
+				Agent:   "bug-spotter",
+				Path:    "/view/project/main.go",
+				Text:    strings.Repeat("[info] L6: this line has more to say\n", 40),
+				Content: e2eContent,
+			},
+		},
+	},
+	{
+		name: "disconnected",
+		msgs: []tea.Msg{
+			opencode.StateMsg{
+				State:   opencode.StateDisconnected,
+				Err:     errors.New("connection refused"),
+				Attempt: 3,
+				RetryIn: 2 * time.Second,
+			},
+		},
+	},
+}
+
+func viewBufferEvent() tui.BufferEventMsg {
+	return tui.BufferEventMsg{
+		Filename:   "main.go",
+		Path:       "/view/project/main.go",
+		Filetype:   "go",
+		CursorLine: 6,
+		CursorCol:  1,
+		LineCount:  7,
+		LastEvent:  "buffer_write",
+		Content:    e2eContent,
+	}
+}
+
+// TestView_Snapshots renders the dashboard in every state at every size and
+// compares it with testdata/golden/view_<state>_<width>x<height>.golden
+func TestView_Snapshots(t *testing.T) {
+	for _, state := range viewStates {
+		for _, size := range viewSizes {
+			name := fmt.Sprintf("view_%s_%dx%d", state.name, size.width, size.height)
+			t.Run(name, func(t *testing.T) {
+				// Nothing listens there; the model never reaches the
+				// server as its commands aren't run
+				var model tea.Model = tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+				model, _ = model.Update(tea.WindowSizeMsg{Width: size.width, Height: size.height})
+				for _, msg := range state.msgs {
+					model, _ = model.Update(msg)
+				}
+				view := model.View()
+				if w, h := lipgloss.Width(view), lipgloss.Height(view); w > size.width || h > size.height {
+					t.Errorf("Expected the view to fit %dx%d, got %dx%d", size.width, size.height, w, h)
+				}
+				checkGolden(t, name, view)
+			})
+		}
+	}
+}
+
+// TestView_BeforeWindowSize checks nothing is laid out before the terminal
+// size is known
+func TestView_BeforeWindowSize(t *testing.T) {
+	model := tui.NewModel(opencode.Config{BaseURL: "http://127.0.0.1:1"})
+	if got := model.View(); got != "Loading..." {
+		t.Errorf("Expected the loading screen, got %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Package logging writes structured logs, tagged with the component they
+// come from, to a rotating file and keeps the latest warnings and errors for
+// the dashboard
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ComponentKey is the attribute naming the part of algopeeps a record comes
+// from
+const ComponentKey = "component"
+
+// current is the handler set up by Setup; nothing is logged before
+var current atomic.Pointer[slog.Handler]
+
+// Setup logs records at level and above to w and hands warnings and errors
+// to recent, when it isn't nil. It also becomes the slog default.
+func Setup(w io.Writer, level slog.Leveler, recent *Recent) {
+	var h slog.Handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
+	if recent != nil {
+		h = tee{h, recent.Handler()}
+	}
+	current.Store(&h)
+	slog.SetDefault(slog.New(h))
+}
+
+// Component returns the logger of a component. It logs through whatever
+// Setup installs, so package-level loggers made before then log too.
+func Component(name string) *slog.Logger {
+	return slog.New(lazy{}).With(ComponentKey, name)
+}
+
+// lazy hands records to the handler set up when they are logged. Attributes
+// and groups are replayed onto it each time.
+type lazy struct {
+	with []func(slog.Handler) slog.Handler
+}
+
+func (l lazy) handler() slog.Handler {
+	p := current.Load()
+	if p == nil {
+		return nil
+	}
+	h := *p
+	for _, fn := range l.with {
+		h = fn(h)
+	}
+	return h
+}
+
+func (l lazy) Enabled(ctx context.Context, level slog.Level) bool {
+	h := l.handler()
+	return h != nil && h.Enabled(ctx, level)
+}
+
+func (l lazy) Handle(ctx context.Context, r slog.Record) error {
+	h := l.handler()
+	if h == nil {
+		return nil
+	}
+	return h.Handle(ctx, r)
+}
+
+func (l lazy) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return lazy{with: append(l.with[:len(l.with):len(l.with)], func(h slog.Handler) slog.Handler {
+		return h.WithAttrs(attrs)
+	})}
+}
+
+func (l lazy) WithGroup(name string) slog.Handler {
+	return lazy{with: append(l.with[:len(l.with):len(l.with)], func(h slog.Handler) slog.Handler {
+		return h.WithGroup(name)
+	})}
+}
+
+// tee hands records to every handler enabled for them
+type tee []slog.Handler
+
+func (t tee) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t tee) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range t {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	hs := make(tee, len(t))
+	for i, h := range t {
+		hs[i] = h.WithAttrs(attrs)
+	}
+	return hs
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	hs := make(tee, len(t))
+	for i, h := range t {
+		hs[i] = h.WithGroup(name)
+	}
+	return hs
+}
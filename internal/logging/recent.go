@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a warning or error kept for the log pane
+type Entry struct {
+	Time      time.Time
+	Level     slog.Level
+	Component string
+	Message   string
+	Attrs     string // The other attributes, as key=value pairs
+}
+
+// Recent keeps the latest warnings and errors logged
+type Recent struct {
+	mu      sync.Mutex
+	entries []Entry // Oldest first
+	size    int
+	total   int
+	notify  func(Entry)
+}
+
+// NewRecent keeps the last size warnings and errors
+func NewRecent(size int) *Recent {
+	return &Recent{size: size}
+}
+
+// Entries returns the entries kept, oldest first
+func (r *Recent) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Total counts every entry ever added, telling new ones apart from those
+// already seen
+func (r *Recent) Total() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.total
+}
+
+// Notify calls fn with every entry added from now on. fn runs in the
+// goroutine that logged, so it must not block.
+func (r *Recent) Notify(fn func(Entry)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notify = fn
+}
+
+func (r *Recent) add(e Entry) {
+	r.mu.Lock()
+	if len(r.entries) == r.size {
+		r.entries = append(r.entries[:0], r.entries[1:]...)
+	}
+	r.entries = append(r.entries, e)
+	r.total++
+	notify := r.notify
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify(e)
+	}
+}
+
+// Handler returns a handler adding the warnings and errors it gets to r
+func (r *Recent) Handler() slog.Handler {
+	return &recentHandler{recent: r}
+}
+
+type recentHandler struct {
+	recent    *Recent
+	component string
+	attrs     []string // Formatted, groups applied
+	group     string   // Prefix of attribute keys, from WithGroup
+}
+
+func (h *recentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn
+}
+
+func (h *recentHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := append([]string(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = appendAttr(attrs, h.group, a)
+		return true
+	})
+	h.recent.add(Entry{
+		Time:      r.Time,
+		Level:     r.Level,
+		Component: h.component,
+		Message:   r.Message,
+		Attrs:     strings.Join(attrs, " "),
+	})
+	return nil
+}
+
+func (h *recentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]string(nil), h.attrs...)
+	for _, a := range attrs {
+		if a.Key == ComponentKey && h.group == "" {
+			c.component = a.Value.String()
+			continue
+		}
+		c.attrs = appendAttr(c.attrs, h.group, a)
+	}
+	return &c
+}
+
+func (h *recentHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.group = h.group + name + "."
+	return &c
+}
+
+// appendAttr formats a as key=value, flattening groups
+func appendAttr(attrs []string, prefix string, a slog.Attr) []string {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			attrs = appendAttr(attrs, prefix, ga)
+		}
+		return attrs
+	}
+	value := a.Value.String()
+	if strings.ContainsAny(value, " \"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	return append(attrs, prefix+a.Key+"="+value)
+}
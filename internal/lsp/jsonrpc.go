@@ -0,0 +1,105 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *responseError   `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// conn reads and writes Content-Length framed JSON-RPC messages
+type conn struct {
+	reader *textproto.Reader
+	mu     sync.Mutex
+	w      io.Writer
+}
+
+func newConn(r io.Reader, w io.Writer) *conn {
+	return &conn{
+		reader: textproto.NewReader(bufio.NewReader(r)),
+		w:      w,
+	}
+}
+
+func (c *conn) read() (*message, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %w", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(c.reader.R, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("failed to decode message: %w", err)
+	}
+	return &msg, nil
+}
+
+func (c *conn) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.w.Write(body)
+	return err
+}
+
+func (c *conn) reply(id *json.RawMessage, result any) error {
+	if result == nil {
+		// A null result must still be present in a successful response
+		result = json.RawMessage("null")
+	}
+	return c.write(&message{ID: id, Result: result})
+}
+
+func (c *conn) replyError(id *json.RawMessage, code int, text string) error {
+	return c.write(&message{ID: id, Error: &responseError{Code: code, Message: text}})
+}
+
+func (c *conn) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode params: %w", err)
+	}
+	return c.write(&message{Method: method, Params: raw})
+}
@@ -0,0 +1,456 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/abhirupda/algopeeps/internal/backend"
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// Server is a language server that puts open documents in front of the
+// council and reports findings as diagnostics and suggested fixes as code
+// actions
+type Server struct {
+	conn     *conn
+	client   backend.AgentBackend
+	debounce time.Duration
+
+	mu       sync.Mutex
+	docs     map[string]*document
+	findings *findings.Store
+	edits    map[string][]protocol.EditProposal
+	shutdown bool
+}
+
+type document struct {
+	uri        string
+	path       string
+	languageID string
+	text       string
+	cursorLine int
+	timer      *time.Timer
+}
+
+// New creates a language server reading requests from r and writing to w.
+// Edits are reviewed once they have been idle for debounce.
+func New(r io.Reader, w io.Writer, client backend.AgentBackend, debounce time.Duration) *Server {
+	return &Server{
+		conn:     newConn(r, w),
+		client:   client,
+		debounce: debounce,
+		docs:     make(map[string]*document),
+		findings: findings.NewStore(),
+		edits:    make(map[string][]protocol.EditProposal),
+	}
+}
+
+// Run serves requests until the client sends exit or closes the stream
+func (s *Server) Run() error {
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if msg.Method == "exit" {
+			s.mu.Lock()
+			clean := s.shutdown
+			s.mu.Unlock()
+			if !clean {
+				return fmt.Errorf("exit before shutdown")
+			}
+			return nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) error {
+	switch msg.Method {
+	case "initialize":
+		return s.conn.reply(msg.ID, InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    textDocumentSyncFull,
+					Save:      SaveOptions{IncludeText: true},
+				},
+				CodeActionProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "algopeeps"},
+		})
+
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		for _, doc := range s.docs {
+			if doc.timer != nil {
+				doc.timer.Stop()
+			}
+		}
+		s.mu.Unlock()
+		return s.conn.reply(msg.ID, nil)
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.didOpen(params)
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.didChange(params)
+
+	case "textDocument/didSave":
+		var params DidSaveTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.didSave(params)
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil
+		}
+		s.didClose(params)
+
+	case "textDocument/codeAction":
+		var params CodeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return s.conn.replyError(msg.ID, codeInvalidParams, err.Error())
+		}
+		return s.conn.reply(msg.ID, s.codeActions(params))
+
+	default:
+		// Requests need an answer, unknown notifications are ignored
+		if msg.ID != nil {
+			return s.conn.replyError(msg.ID, codeMethodNotFound, "method not found: "+msg.Method)
+		}
+	}
+	return nil
+}
+
+func (s *Server) didOpen(params DidOpenTextDocumentParams) {
+	item := params.TextDocument
+
+	s.mu.Lock()
+	s.docs[item.URI] = &document{
+		uri:        item.URI,
+		path:       uriToPath(item.URI),
+		languageID: item.LanguageID,
+		text:       item.Text,
+		cursorLine: 1,
+	}
+	s.mu.Unlock()
+
+	s.review(item.URI, protocol.EventBufferEnter)
+}
+
+func (s *Server) didChange(params DidChangeTextDocumentParams) {
+	if len(params.ContentChanges) == 0 {
+		return
+	}
+	uri := params.TextDocument.URI
+
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+
+	// Full sync: the last change holds the whole document
+	text := params.ContentChanges[len(params.ContentChanges)-1].Text
+	doc.cursorLine = firstChangedLine(doc.text, text)
+	doc.text = text
+
+	// Line numbers of suggested fixes no longer hold
+	delete(s.edits, doc.path)
+	changed := s.findings.Prune(doc.path, text)
+
+	if doc.timer != nil {
+		doc.timer.Stop()
+	}
+	doc.timer = time.AfterFunc(s.debounce, func() {
+		s.review(uri, protocol.EventTextChanged)
+	})
+	s.mu.Unlock()
+
+	if changed {
+		s.publish(uri)
+	}
+}
+
+func (s *Server) didSave(params DidSaveTextDocumentParams) {
+	uri := params.TextDocument.URI
+
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	if params.Text != nil {
+		doc.text = *params.Text
+	}
+	if doc.timer != nil {
+		doc.timer.Stop()
+	}
+	s.mu.Unlock()
+
+	s.review(uri, protocol.EventBufferWrite)
+}
+
+func (s *Server) didClose(params DidCloseTextDocumentParams) {
+	uri := params.TextDocument.URI
+
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if ok {
+		if doc.timer != nil {
+			doc.timer.Stop()
+		}
+		delete(s.docs, uri)
+		delete(s.edits, doc.path)
+		s.findings.Clear(doc.path)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		_ = s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: []Diagnostic{},
+		})
+	}
+}
+
+// review sends a snapshot of the document to the council in the background
+func (s *Server) review(uri string, event protocol.EventType) {
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if !ok || s.shutdown {
+		s.mu.Unlock()
+		return
+	}
+	path := doc.path
+	snapshot := doc.text
+	req := council.Request{
+		Path:       path,
+		Filename:   path,
+		Filetype:   doc.languageID,
+		CursorLine: doc.cursorLine,
+		Event:      string(event),
+		Content:    snapshot,
+	}
+	s.mu.Unlock()
+
+	go func() {
+		if err := s.client.EnsureSession(); err != nil {
+			s.logf(messageTypeError, "Session: %v", err)
+			return
+		}
+
+		responses := council.Review(s.client, req)
+
+		s.mu.Lock()
+		doc, ok := s.docs[uri]
+		if !ok {
+			s.mu.Unlock()
+			return
+		}
+		current := doc.text == snapshot
+		for _, res := range responses {
+			if res.Err != nil {
+				continue
+			}
+			s.findings.Replace(path, res.Agent, findings.Parse(res.Agent, path, res.Text, snapshot))
+			if current {
+				edits := slices.DeleteFunc(s.edits[path], func(e protocol.EditProposal) bool {
+					return e.Agent == res.Agent
+				})
+				s.edits[path] = append(edits, findings.ParseEdits(res.Agent, path, res.Text)...)
+			}
+		}
+		if !current {
+			s.findings.Prune(path, doc.text)
+		}
+		s.mu.Unlock()
+
+		for _, res := range responses {
+			if res.Err != nil {
+				s.logf(messageTypeWarning, "%s: %v", res.Agent, res.Err)
+			}
+		}
+		s.publish(uri)
+	}()
+}
+
+// publish sends the current findings for a document as diagnostics
+func (s *Server) publish(uri string) {
+	s.mu.Lock()
+	doc, ok := s.docs[uri]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	fs := s.findings.File(doc.path)
+	lines := strings.Split(doc.text, "\n")
+	s.mu.Unlock()
+
+	diags := make([]Diagnostic, 0, len(fs))
+	for _, f := range fs {
+		diags = append(diags, Diagnostic{
+			Range:    toRange(lines, f.Range),
+			Severity: toSeverity(f.Severity),
+			Source:   f.Agent,
+			Message:  f.Message,
+		})
+	}
+
+	_ = s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: diags,
+	})
+}
+
+// codeActions offers the suggested fixes that touch the requested range
+func (s *Server) codeActions(params CodeActionParams) []CodeAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actions := []CodeAction{}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return actions
+	}
+
+	lines := strings.Split(doc.text, "\n")
+	for _, e := range s.edits[doc.path] {
+		start, end := e.Range.Start.Line-1, e.Range.End.Line-1
+		if end < params.Range.Start.Line || start > params.Range.End.Line {
+			continue
+		}
+		edit, ok := lineEdit(lines, e)
+		if !ok {
+			continue
+		}
+		actions = append(actions, CodeAction{
+			Title: fmt.Sprintf("Apply fix from %s (L%d-L%d)", e.Agent, e.Range.Start.Line, e.Range.End.Line),
+			Kind:  "quickfix",
+			Edit: WorkspaceEdit{
+				Changes: map[string][]TextEdit{doc.uri: {edit}},
+			},
+		})
+	}
+	return actions
+}
+
+func (s *Server) logf(level int, format string, args ...any) {
+	_ = s.conn.notify("window/logMessage", LogMessageParams{
+		Type:    level,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lineEdit turns a line-wise edit proposal into an LSP text edit
+// lineEdit turns a line-wise edit into a text edit of the document. It
+// reports false when the edit starts past the end of the document.
+func lineEdit(lines []string, e protocol.EditProposal) (TextEdit, bool) {
+	if e.Range.Start.Line < 1 || e.Range.Start.Line > len(lines) {
+		return TextEdit{}, false
+	}
+	start := Position{Line: e.Range.Start.Line - 1}
+	if e.Range.End.Line < len(lines) {
+		// Replace through the start of the following line
+		return TextEdit{
+			Range:   Range{Start: start, End: Position{Line: e.Range.End.Line}},
+			NewText: e.Text + "\n",
+		}, true
+	}
+
+	last := len(lines) - 1
+	return TextEdit{
+		Range:   Range{Start: start, End: Position{Line: last, Character: utf16Len(lines[last])}},
+		NewText: e.Text,
+	}, true
+}
+
+// toRange converts a range in lines to LSP's, which counts characters in
+// UTF-16 code units
+func toRange(lines []string, r protocol.Range) Range {
+	return Range{Start: toPosition(lines, r.Start), End: toPosition(lines, r.End)}
+}
+
+func toPosition(lines []string, p protocol.Position) Position {
+	pos := Position{Line: p.Line - 1}
+	if pos.Line >= 0 && pos.Line < len(lines) {
+		line := lines[pos.Line]
+		pos.Character = utf16Len(line[:min(max(p.Col, 0), len(line))])
+	}
+	return pos
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+func toSeverity(sev findings.Severity) DiagnosticSeverity {
+	switch sev {
+	case findings.SeverityError:
+		return SeverityError
+	case findings.SeverityWarning:
+		return SeverityWarning
+	case findings.SeverityHint:
+		return SeverityHint
+	default:
+		return SeverityInformation
+	}
+}
+
+// firstChangedLine returns the 1-based line where two versions of a document
+// start to differ, standing in for the cursor LSP doesn't report
+func firstChangedLine(old, new string) int {
+	oldLines := strings.Split(old, "\n")
+	newLines := strings.Split(new, "\n")
+	for i := 0; i < len(oldLines) && i < len(newLines); i++ {
+		if oldLines[i] != newLines[i] {
+			return i + 1
+		}
+	}
+	return min(len(oldLines), len(newLines))
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
@@ -3,13 +3,18 @@ package opencode
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/abhirupda/algopeeps/internal/backend"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/sst/opencode-sdk-go"
 	"github.com/sst/opencode-sdk-go/option"
 )
 
+var _ backend.AgentBackend = (*Client)(nil)
+
 type Config struct {
 	BaseURL string
 }
@@ -27,6 +32,26 @@ type Client struct {
 	ctx       context.Context
 	cancel    context.CancelFunc
 	connected bool
+	sessionMu sync.Mutex   // Held while the session is checked or replaced
+	idMu      sync.RWMutex // Guards sessionID and connected, for readers not holding sessionMu
+	project   string       // Named in the titles of new sessions
+
+	// messageAgents maps assistant message IDs to the agent answering
+	messageAgents map[string]string
+	agentsMu      sync.Mutex
+
+	// streamed counts the bytes of each text part forwarded from the event
+	// stream, so text missed while it was down can be recovered
+	streamed   map[string]int
+	streamedMu sync.Mutex
+
+	onDelta backend.DeltaFunc        // Receives streamed text instead of the program
+	usage   map[string]backend.Usage // By agent
+	usageMu sync.Mutex
+
+	state          State
+	stateMu        sync.Mutex
+	sessionChanged chan struct{} // Signalled when the session is replaced
 }
 
 func NewClient(cfg Config) (*Client, error) {
@@ -41,26 +66,45 @@ func NewClient(cfg Config) (*Client, error) {
 	)
 
 	return &Client{
-		sdk:    sdk,
-		config: cfg,
-		ctx:    ctx,
-		cancel: cancel,
+		sdk:            sdk,
+		config:         cfg,
+		ctx:            ctx,
+		cancel:         cancel,
+		messageAgents:  make(map[string]string),
+		streamed:       make(map[string]int),
+		usage:          make(map[string]backend.Usage),
+		sessionChanged: make(chan struct{}, 1),
 	}, nil
 }
 
 func (c *Client) EnsureSession() error {
-	const maxRetries = 3
-	const retryDelay = time.Second
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
 
 	if c.sessionID != "" {
 		_, err := c.sdk.Session.Get(c.ctx, c.sessionID, opencode.SessionGetParams{})
 		if err == nil {
-			c.connected = true
+			c.setSession(c.sessionID, true)
 			return nil
 		}
-		c.sessionID = ""
-		c.connected = false
+		logger.Warn("session lost, creating a new one", "session", c.sessionID, "err", err)
+		c.setSession("", false)
 	}
+	return c.createSession()
+}
+
+// NewSession replaces the session with a new one, leaving the old one on the
+// server
+func (c *Client) NewSession() error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	return c.createSession()
+}
+
+// createSession makes a new session current; c.sessionMu must be held
+func (c *Client) createSession() error {
+	const maxRetries = 3
+	const retryDelay = time.Second
 
 	var lastErr error
 	for i := 0; i < maxRetries; i++ {
@@ -68,107 +112,164 @@ func (c *Client) EnsureSession() error {
 			time.Sleep(retryDelay)
 		}
 
-		title := fmt.Sprintf("Algopeeps Council - %s", time.Now().Format("2006-01-02"))
 		session, err := c.sdk.Session.New(c.ctx, opencode.SessionNewParams{
-			Title: opencode.F(title),
+			Title: opencode.F(c.sessionTitle()),
 		})
 		if err != nil {
+			logger.Debug("creating session", "attempt", i+1, "err", err)
 			lastErr = err
 			continue
 		}
 
-		c.sessionID = session.ID
-		c.connected = true
+		logger.Info("session created", "session", session.ID)
+		c.setSession(session.ID, true)
+		c.sessionReplaced()
 		return nil
 	}
 
-	c.connected = false
+	c.setSession("", false)
 	return fmt.Errorf("failed to create session after %d retries: %w", maxRetries, lastErr)
 }
 
+// Reattach makes EnsureSession resume the session id instead of creating a
+// new one, if the session still exists
+func (c *Client) Reattach(id string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	if id != c.sessionID {
+		c.setSession(id, false)
+		c.sessionReplaced()
+	}
+}
+
+// setSession replaces the session; c.sessionMu must be held
+func (c *Client) setSession(id string, connected bool) {
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+	c.sessionID = id
+	c.connected = connected
+}
+
 func (c *Client) IsConnected() bool {
+	c.idMu.RLock()
+	defer c.idMu.RUnlock()
 	return c.connected
 }
 
 func (c *Client) SessionID() string {
+	c.idMu.RLock()
+	defer c.idMu.RUnlock()
 	return c.sessionID
 }
 
 func (c *Client) SendPrompt(agent, prompt string) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no session available, call EnsureSession first")
+	_, err := c.Prompt(agent, prompt)
+	return err
+}
+
+// Prompt sends a prompt to an agent and waits for the reply text
+func (c *Client) Prompt(agent, prompt string) (string, error) {
+	sessionID := c.SessionID()
+	if sessionID == "" {
+		return "", fmt.Errorf("no session available, call EnsureSession first")
 	}
 
 	params := opencode.SessionPromptParams{
 		Agent: opencode.F(agent),
 		Parts: opencode.F([]opencode.SessionPromptParamsPartUnion{
 			opencode.TextPartInputParam{
+				Type: opencode.F(opencode.TextPartInputTypeText),
 				Text: opencode.F(prompt),
 			},
 		}),
 	}
 
-	_, err := c.sdk.Session.Prompt(c.ctx, c.sessionID, params)
+	res, err := c.sdk.Session.Prompt(c.ctx, sessionID, params)
 	if err != nil {
-		return fmt.Errorf("failed to send prompt: %w", err)
+		return "", fmt.Errorf("failed to send prompt: %w", err)
 	}
 
-	return nil
+	c.usageMu.Lock()
+	u := c.usage[agent]
+	u.InputTokens += int(res.Info.Tokens.Input)
+	u.OutputTokens += int(res.Info.Tokens.Output)
+	u.Cost += res.Info.Cost
+	c.usage[agent] = u
+	c.usageMu.Unlock()
+
+	var text strings.Builder
+	for _, part := range res.Parts {
+		if part.Type == opencode.PartTypeText {
+			text.WriteString(part.Text)
+		}
+	}
+
+	return text.String(), nil
 }
 
-type AgentTextMsg struct {
-	Agent string
-	Text  string
+// Chat is Prompt: agents see the whole session either way
+func (c *Client) Chat(agent, prompt string) (string, error) {
+	return c.Prompt(agent, prompt)
 }
 
-type AgentIdleMsg struct {
-	Agent string
+// OnDelta makes the event stream pass agent text to fn rather than send it
+// to the program. Text only streams while SubscribeEvents runs.
+func (c *Client) OnDelta(fn backend.DeltaFunc) {
+	c.agentsMu.Lock()
+	defer c.agentsMu.Unlock()
+	c.onDelta = fn
 }
 
-func (c *Client) SubscribeEvents(ctx context.Context, program *tea.Program) error {
-	if c.sessionID == "" {
-		return fmt.Errorf("no session available, call EnsureSession first")
+// sendText forwards streamed agent text
+func (c *Client) sendText(program *tea.Program, agent, text string) {
+	c.agentsMu.Lock()
+	fn := c.onDelta
+	c.agentsMu.Unlock()
+	if fn != nil {
+		fn(agent, text)
+		return
 	}
+	program.Send(AgentTextMsg{Agent: agent, Text: text})
+}
 
-	streamCtx, streamCancel := context.WithCancel(ctx)
-	defer streamCancel()
-
-	stream := c.sdk.Event.ListStreaming(streamCtx, opencode.EventListParams{})
-
-	for stream.Next() {
-		event := stream.Current()
-
-		switch event.Type {
-		case opencode.EventListResponseTypeMessagePartUpdated:
-			if partEvent, ok := event.AsUnion().(opencode.EventListResponseEventMessagePartUpdated); ok {
-				agentName := c.extractAgentName(partEvent.Properties.Part)
-
-				if partEvent.Properties.Delta != "" {
-					program.Send(AgentTextMsg{
-						Agent: agentName,
-						Text:  partEvent.Properties.Delta,
-					})
-				}
-			}
-
-		case opencode.EventListResponseTypeSessionIdle:
-			if idleEvent, ok := event.AsUnion().(opencode.EventListResponseEventSessionIdle); ok {
-				if idleEvent.Properties.SessionID == c.sessionID {
-					program.Send(AgentIdleMsg{Agent: "code-reviewer"})
-					program.Send(AgentIdleMsg{Agent: "bug-spotter"})
-				}
-			}
-		}
+// Abort stops the reply in progress. Agents share the session, so this
+// stops every agent's reply, not just agent's.
+func (c *Client) Abort(agent string) error {
+	sessionID := c.SessionID()
+	if sessionID == "" {
+		return nil
 	}
-
-	if err := stream.Err(); err != nil {
-		return fmt.Errorf("stream error: %w", err)
+	if _, err := c.sdk.Session.Abort(c.ctx, sessionID, opencode.SessionAbortParams{}); err != nil {
+		return fmt.Errorf("failed to abort: %w", err)
 	}
-
 	return nil
 }
 
+// Usage returns the tokens agent used and their cost, as OpenCode reports
+// them
+func (c *Client) Usage(agent string) backend.Usage {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	return c.usage[agent]
+}
+
+type AgentTextMsg struct {
+	Agent string
+	Text  string
+}
+
+type AgentIdleMsg struct {
+	Agent string
+}
+
 func (c *Client) extractAgentName(part opencode.Part) string {
+	c.agentsMu.Lock()
+	agent, ok := c.messageAgents[part.MessageID]
+	c.agentsMu.Unlock()
+	if ok {
+		return agent
+	}
+
 	if part.Source != nil {
 		if agentSource, ok := part.Source.(opencode.AgentPartSource); ok {
 			return agentSource.Value
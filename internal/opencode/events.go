@@ -0,0 +1,206 @@
+package opencode
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/sst/opencode-sdk-go"
+)
+
+const (
+	reconnectMinDelay = 500 * time.Millisecond
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// SubscribeEvents runs the client lifecycle until ctx is done or the client
+// is closed, reporting each transition to program as a StateMsg. It makes
+// sure a session exists, then forwards agent text from the OpenCode event
+// stream. When the stream drops it reconnects with exponential backoff,
+// checks the session still exists and recovers the text sent while it was
+// down. When the session is replaced the stream restarts for the new one.
+func (c *Client) SubscribeEvents(ctx context.Context, program *tea.Program) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(c.ctx, cancel)
+	defer stop()
+
+	attempt := 0
+	var down time.Time
+	for {
+		c.setState(program, StateMsg{State: StateConnecting, Attempt: attempt})
+		err := c.EnsureSession()
+		if err == nil {
+			// The session in hand is the one to stream for
+			select {
+			case <-c.sessionChanged:
+			default:
+			}
+			c.setState(program, StateMsg{State: StateSessionReady, SessionID: c.SessionID()})
+
+			var connected, replaced bool
+			connected, replaced, err = c.streamSession(ctx, program, func() {
+				// Recover once events flow again, so nothing falls between
+				if !down.IsZero() {
+					c.recoverMissed(program, down)
+				}
+				attempt = 0
+				down = time.Time{}
+				c.setState(program, StateMsg{State: StateStreaming, SessionID: c.SessionID()})
+			})
+			if replaced {
+				continue
+			}
+			if connected {
+				down = time.Now()
+			}
+		}
+		if ctx.Err() != nil {
+			c.setState(program, StateMsg{State: StateDisconnected})
+			return ctx.Err()
+		}
+		if down.IsZero() {
+			down = time.Now()
+		}
+		if err == nil {
+			err = errors.New("event stream closed")
+		}
+
+		delay := backoff(attempt)
+		attempt++
+		c.setState(program, StateMsg{State: StateDisconnected, Err: err, Attempt: attempt, RetryIn: delay})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// streamSession streams events until the stream ends or the session is
+// replaced, which it reports
+func (c *Client) streamSession(ctx context.Context, program *tea.Program, onConnect func()) (connected, replaced bool, err error) {
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+
+	var sessionGone atomic.Bool
+	go func() {
+		select {
+		case <-c.sessionChanged:
+			sessionGone.Store(true)
+			cancelStream()
+		case <-streamCtx.Done():
+		}
+	}()
+
+	connected, err = c.stream(streamCtx, program, onConnect)
+	return connected, sessionGone.Load() && ctx.Err() == nil, err
+}
+
+// backoff returns the delay before reconnection attempt n: exponential, capped
+// and jittered so clients don't reconnect in lockstep
+func backoff(n int) time.Duration {
+	d := reconnectMaxDelay
+	if n < 16 {
+		d = min(reconnectMinDelay<<n, reconnectMaxDelay)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// stream reads events until the stream ends. onConnect runs on the first
+// event; connected reports whether it did.
+func (c *Client) stream(ctx context.Context, program *tea.Program, onConnect func()) (connected bool, err error) {
+	stream := c.sdk.Event.ListStreaming(ctx, opencode.EventListParams{})
+	defer stream.Close()
+
+	for stream.Next() {
+		if !connected {
+			connected = true
+			onConnect()
+		}
+		c.handleEvent(program, stream.Current())
+	}
+	return connected, stream.Err()
+}
+
+func (c *Client) handleEvent(program *tea.Program, event opencode.EventListResponse) {
+	switch event.Type {
+	case opencode.EventListResponseTypeMessageUpdated:
+		if msgEvent, ok := event.AsUnion().(opencode.EventListResponseEventMessageUpdated); ok {
+			info := msgEvent.Properties.Info
+			if info.Role == opencode.MessageRoleAssistant && info.Mode != "" {
+				c.agentsMu.Lock()
+				c.messageAgents[info.ID] = info.Mode
+				c.agentsMu.Unlock()
+			}
+		}
+
+	case opencode.EventListResponseTypeMessagePartUpdated:
+		if partEvent, ok := event.AsUnion().(opencode.EventListResponseEventMessagePartUpdated); ok {
+			part := partEvent.Properties.Part
+			delta := partEvent.Properties.Delta
+			if delta != "" && part.SessionID == c.SessionID() {
+				c.streamedMu.Lock()
+				c.streamed[part.ID] += len(delta)
+				c.streamedMu.Unlock()
+
+				c.sendText(program, c.extractAgentName(part), delta)
+			}
+		}
+
+	case opencode.EventListResponseTypeSessionIdle:
+		if idleEvent, ok := event.AsUnion().(opencode.EventListResponseEventSessionIdle); ok {
+			if idleEvent.Properties.SessionID == c.SessionID() {
+				// Nothing is streaming any more, no part can be missed
+				c.streamedMu.Lock()
+				clear(c.streamed)
+				c.streamedMu.Unlock()
+
+				program.Send(AgentIdleMsg{Agent: "code-reviewer"})
+				program.Send(AgentIdleMsg{Agent: "bug-spotter"})
+			}
+		}
+	}
+}
+
+// recoverMissed forwards the text of assistant messages that was produced
+// while the stream was down since down: the rest of parts that were streaming,
+// and messages created during the gap
+func (c *Client) recoverMissed(program *tea.Program, down time.Time) {
+	sessionID := c.SessionID()
+	messages, err := c.sdk.Session.Messages(c.ctx, sessionID, opencode.SessionMessagesParams{})
+	if err != nil {
+		logger.Warn("recovering text missed while disconnected", "session", sessionID, "err", err)
+		return
+	}
+
+	since := float64(down.UnixMilli())
+	c.streamedMu.Lock()
+	defer c.streamedMu.Unlock()
+	for _, msg := range *messages {
+		assistant, ok := msg.Info.AsUnion().(opencode.AssistantMessage)
+		if !ok {
+			continue
+		}
+		for _, part := range msg.Parts {
+			if part.Type != opencode.PartTypeText {
+				continue
+			}
+			sent, streaming := c.streamed[part.ID]
+			if !streaming && assistant.Time.Created < since {
+				continue
+			}
+			if len(part.Text) > sent {
+				c.sendText(program, assistant.Mode, part.Text[sent:])
+				c.streamed[part.ID] = len(part.Text)
+			}
+		}
+		if assistant.Time.Completed > 0 && assistant.Time.Completed >= since {
+			program.Send(AgentIdleMsg{Agent: assistant.Mode})
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package opencode
+
+import (
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/logging"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var logger = logging.Component("opencode")
+
+// State is where the client is in its connection lifecycle. It moves
+// disconnected → connecting → session ready → streaming, falls back to
+// disconnected when the server goes away, and back to connecting when the
+// session is replaced.
+type State int
+
+const (
+	StateDisconnected State = iota
+	StateConnecting
+	StateSessionReady
+	StateStreaming
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateSessionReady:
+		return "session ready"
+	case StateStreaming:
+		return "streaming"
+	default:
+		return "disconnected"
+	}
+}
+
+// StateMsg reports a lifecycle transition
+type StateMsg struct {
+	State     State
+	SessionID string        // Session in use, from StateSessionReady on
+	Err       error         // Why the client is disconnected
+	Attempt   int           // Reconnection attempts so far
+	RetryIn   time.Duration // Delay before the next attempt
+}
+
+// State returns the current lifecycle state
+func (c *Client) State() State {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.state
+}
+
+func (c *Client) setState(program *tea.Program, msg StateMsg) {
+	c.stateMu.Lock()
+	c.state = msg.State
+	c.stateMu.Unlock()
+
+	switch {
+	case msg.Err != nil && msg.Attempt == 1:
+		logger.Warn("disconnected", "err", msg.Err, "retry_in", msg.RetryIn)
+	case msg.Err != nil:
+		logger.Info("still disconnected", "err", msg.Err, "attempt", msg.Attempt, "retry_in", msg.RetryIn)
+	default:
+		logger.Info(msg.State.String(), "session", msg.SessionID)
+	}
+	program.Send(msg)
+}
+
+// sessionReplaced wakes the lifecycle so the stream follows a new session;
+// c.sessionMu must be held
+func (c *Client) sessionReplaced() {
+	select {
+	case c.sessionChanged <- struct{}{}:
+	default:
+	}
+}
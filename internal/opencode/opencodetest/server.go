@@ -0,0 +1,476 @@
+// Package opencodetest runs a fake OpenCode server in-process, implementing
+// the part of the HTTP API algopeeps uses, so the client and everything on
+// top of it can be tested without opencode serve
+package opencodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultReply is what agents without a scripted reply answer
+const DefaultReply = "No issues found."
+
+// Server is a fake OpenCode server. Agents answer prompts with the replies
+// scripted for them, streamed word by word on /event like OpenCode does.
+type Server struct {
+	URL string
+
+	srv *httptest.Server
+
+	mu             sync.Mutex
+	sessions       map[string]*session
+	nextID         int
+	reply          func(agent, prompt string) string
+	replies        map[string]string // By agent
+	prompts        []Prompt
+	streams        map[*stream]struct{}
+	refuseStreams  bool
+	streamRequests int
+	listed         []string // Sessions whose messages were requested
+}
+
+// Prompt is a prompt the server received
+type Prompt struct {
+	SessionID string
+	Agent     string
+	Text      string
+}
+
+type session struct {
+	info     sessionJSON
+	messages []messageJSON
+}
+
+// stream is an open /event connection
+type stream struct {
+	events chan []byte
+	drop   chan struct{}
+	done   chan struct{}
+}
+
+// NewServer starts a fake OpenCode server; Close shuts it down
+func NewServer() *Server {
+	s := &Server{
+		sessions: make(map[string]*session),
+		replies:  make(map[string]string),
+		streams:  make(map[*stream]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config", s.handleConfig)
+	mux.HandleFunc("GET /event", s.handleEvents)
+	mux.HandleFunc("GET /session", s.handleListSessions)
+	mux.HandleFunc("POST /session", s.handleNewSession)
+	mux.HandleFunc("GET /session/{id}", s.handleGetSession)
+	mux.HandleFunc("PATCH /session/{id}", s.handleUpdateSession)
+	mux.HandleFunc("DELETE /session/{id}", s.handleDeleteSession)
+	mux.HandleFunc("GET /session/{id}/message", s.handleMessages)
+	mux.HandleFunc("POST /session/{id}/message", s.handlePrompt)
+	mux.HandleFunc("POST /session/{id}/abort", s.handleAbort)
+
+	s.srv = httptest.NewServer(mux)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Handler returns the handler serving the fake API, to serve it on another
+// listener
+func (s *Server) Handler() http.Handler {
+	return s.srv.Config.Handler
+}
+
+// Close drops the event streams and shuts the server down
+func (s *Server) Close() {
+	s.DropStreams()
+	s.srv.Close()
+}
+
+// Reply scripts the reply of agent to every prompt
+func (s *Server) Reply(agent, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[agent] = text
+}
+
+// ReplyFunc makes fn answer every prompt, overriding Reply
+func (s *Server) ReplyFunc(fn func(agent, prompt string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reply = fn
+}
+
+// Prompts returns the prompts received so far
+func (s *Server) Prompts() []Prompt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.prompts)
+}
+
+// SessionIDs returns the sessions that exist, oldest first
+func (s *Server) SessionIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// RemoveSession deletes a session behind the client's back, as when it was
+// deleted from another OpenCode client
+func (s *Server) RemoveSession(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// Emit sends an event to every open event stream
+func (s *Server) Emit(eventType string, properties any) {
+	data, err := json.Marshal(map[string]any{"type": eventType, "properties": properties})
+	if err != nil {
+		panic(err)
+	}
+
+	s.mu.Lock()
+	streams := make([]*stream, 0, len(s.streams))
+	for st := range s.streams {
+		streams = append(streams, st)
+	}
+	s.mu.Unlock()
+
+	for _, st := range streams {
+		select {
+		case st.events <- data:
+		case <-st.done:
+		}
+	}
+}
+
+// DropStreams ends the open event streams, as when the connection breaks
+func (s *Server) DropStreams() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for st := range s.streams {
+		close(st.drop)
+		delete(s.streams, st)
+	}
+}
+
+// RefuseStreams makes /event fail while refuse is set, keeping clients from
+// reconnecting
+func (s *Server) RefuseStreams(refuse bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refuseStreams = refuse
+}
+
+// StreamRequests returns how many times /event was requested
+func (s *Server) StreamRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streamRequests
+}
+
+// MessageRequests returns the sessions whose messages were requested, in
+// order
+func (s *Server) MessageRequests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.listed)
+}
+
+// OpenStreams returns how many event streams are open
+func (s *Server) OpenStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.streamRequests++
+	if s.refuseStreams {
+		s.mu.Unlock()
+		http.Error(w, "event stream refused", http.StatusServiceUnavailable)
+		return
+	}
+	st := &stream{events: make(chan []byte, 64), drop: make(chan struct{}), done: make(chan struct{})}
+	s.streams[st] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		close(st.done)
+		s.mu.Lock()
+		delete(s.streams, st)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	write := func(data []byte) {
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// OpenCode greets every new stream
+	write([]byte(`{"type":"server.connected","properties":{}}`))
+	for {
+		select {
+		case data := <-st.events:
+			write(data)
+		case <-st.drop:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	list := make([]sessionJSON, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		list = append(list, sess.info)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(list, func(a, b sessionJSON) int { return strings.Compare(a.ID, b.ID) })
+	writeJSON(w, list)
+}
+
+func (s *Server) handleNewSession(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Title string `json:"title"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&params)
+
+	s.mu.Lock()
+	now := millis(time.Now())
+	info := sessionJSON{
+		ID:        s.newID("ses"),
+		Directory: "/",
+		ProjectID: "fake",
+		Title:     params.Title,
+		Version:   "fake",
+		Time:      sessionTimeJSON{Created: now, Updated: now},
+	}
+	s.sessions[info.ID] = &session{info: info}
+	s.mu.Unlock()
+
+	writeJSON(w, info)
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *Server) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Title string `json:"title"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&params)
+
+	s.mu.Lock()
+	sess, ok := s.sessions[r.PathValue("id")]
+	if ok && params.Title != "" {
+		sess.info.Title = params.Title
+		sess.info.Time.Updated = millis(time.Now())
+	}
+	var info sessionJSON
+	if ok {
+		info = sess.info
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		notFound(w, r.PathValue("id"))
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	_, ok := s.sessions[r.PathValue("id")]
+	delete(s.sessions, r.PathValue("id"))
+	s.mu.Unlock()
+
+	if !ok {
+		notFound(w, r.PathValue("id"))
+		return
+	}
+	writeJSON(w, true)
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.listed = append(s.listed, r.PathValue("id"))
+	sess, ok := s.sessions[r.PathValue("id")]
+	var messages []messageJSON
+	if ok {
+		messages = slices.Clone(sess.messages)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		notFound(w, r.PathValue("id"))
+		return
+	}
+	if messages == nil {
+		messages = []messageJSON{}
+	}
+	writeJSON(w, messages)
+}
+
+func (s *Server) handleAbort(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.session(w, r); !ok {
+		return
+	}
+	writeJSON(w, true)
+}
+
+// handlePrompt answers a prompt with the agent's scripted reply, streaming
+// it on /event before answering the request, like OpenCode
+func (s *Server) handlePrompt(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Agent string `json:"agent"`
+		Parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"parts"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var prompt strings.Builder
+	for _, part := range params.Parts {
+		if part.Type == "text" {
+			prompt.WriteString(part.Text)
+		}
+	}
+
+	id := r.PathValue("id")
+	s.mu.Lock()
+	if _, ok := s.sessions[id]; !ok {
+		s.mu.Unlock()
+		notFound(w, id)
+		return
+	}
+	s.prompts = append(s.prompts, Prompt{SessionID: id, Agent: params.Agent, Text: prompt.String()})
+	reply := s.replyTo(params.Agent, prompt.String())
+
+	now := millis(time.Now())
+	user := messageInfoJSON{ID: s.newID("msg"), Role: "user", SessionID: id, Time: messageTimeJSON{Created: now}}
+	assistant := messageInfoJSON{
+		ID:         s.newID("msg"),
+		Role:       "assistant",
+		SessionID:  id,
+		Mode:       params.Agent,
+		ModelID:    "fake",
+		ProviderID: "fake",
+		ParentID:   user.ID,
+		Path:       &pathJSON{Cwd: "/", Root: "/"},
+		System:     []string{},
+		Time:       messageTimeJSON{Created: now},
+		Tokens: &tokensJSON{
+			Input:  float64(len(strings.Fields(prompt.String()))),
+			Output: float64(len(strings.Fields(reply))),
+		},
+	}
+	userPart := partJSON{ID: s.newID("prt"), MessageID: user.ID, SessionID: id, Type: "text", Text: prompt.String()}
+	part := partJSON{ID: s.newID("prt"), MessageID: assistant.ID, SessionID: id, Type: "text"}
+	s.mu.Unlock()
+
+	s.Emit("message.updated", map[string]any{"info": assistant})
+	for _, delta := range strings.SplitAfter(reply, " ") {
+		if delta == "" {
+			continue
+		}
+		part.Text += delta
+		s.Emit("message.part.updated", map[string]any{"part": part, "delta": delta})
+	}
+	assistant.Time.Completed = millis(time.Now())
+	s.Emit("message.updated", map[string]any{"info": assistant})
+
+	s.mu.Lock()
+	if sess, ok := s.sessions[id]; ok {
+		sess.messages = append(sess.messages,
+			messageJSON{Info: user, Parts: []partJSON{userPart}},
+			messageJSON{Info: assistant, Parts: []partJSON{part}},
+		)
+		sess.info.Time.Updated = assistant.Time.Completed
+	}
+	s.mu.Unlock()
+
+	s.Emit("session.idle", map[string]any{"sessionID": id})
+	writeJSON(w, messageJSON{Info: assistant, Parts: []partJSON{part}})
+}
+
+// replyTo returns the reply of agent to prompt; s.mu must be held
+func (s *Server) replyTo(agent, prompt string) string {
+	if s.reply != nil {
+		return s.reply(agent, prompt)
+	}
+	if text, ok := s.replies[agent]; ok {
+		return text
+	}
+	return DefaultReply
+}
+
+// newID returns a new ID with prefix, in creation order; s.mu must be held
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s_%08d", prefix, s.nextID)
+}
+
+// session returns the session the request is about, or answers it is not
+// found
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (sessionJSON, bool) {
+	s.mu.Lock()
+	sess, ok := s.sessions[r.PathValue("id")]
+	var info sessionJSON
+	if ok {
+		info = sess.info
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		notFound(w, r.PathValue("id"))
+	}
+	return info, ok
+}
+
+func notFound(w http.ResponseWriter, id string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(map[string]any{"name": "NotFoundError", "data": map[string]string{"message": "session " + id + " not found"}})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func millis(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
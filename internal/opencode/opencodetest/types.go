@@ -0,0 +1,68 @@
+package opencodetest
+
+// The JSON the fake sends, the subset of OpenCode's the SDK reads
+
+type sessionJSON struct {
+	ID        string          `json:"id"`
+	Directory string          `json:"directory"`
+	ProjectID string          `json:"projectID"`
+	Time      sessionTimeJSON `json:"time"`
+	Title     string          `json:"title"`
+	Version   string          `json:"version"`
+	ParentID  string          `json:"parentID,omitempty"`
+}
+
+type sessionTimeJSON struct {
+	Created float64 `json:"created"`
+	Updated float64 `json:"updated"`
+}
+
+type messageJSON struct {
+	Info  messageInfoJSON `json:"info"`
+	Parts []partJSON      `json:"parts"`
+}
+
+// messageInfoJSON is a user or assistant message; the assistant fields are
+// left out of user messages
+type messageInfoJSON struct {
+	ID         string          `json:"id"`
+	Role       string          `json:"role"`
+	SessionID  string          `json:"sessionID"`
+	Time       messageTimeJSON `json:"time"`
+	Mode       string          `json:"mode,omitempty"`
+	ModelID    string          `json:"modelID,omitempty"`
+	ProviderID string          `json:"providerID,omitempty"`
+	ParentID   string          `json:"parentID,omitempty"`
+	Path       *pathJSON       `json:"path,omitempty"`
+	System     []string        `json:"system,omitempty"`
+	Cost       float64         `json:"cost"`
+	Tokens     *tokensJSON     `json:"tokens,omitempty"`
+}
+
+type messageTimeJSON struct {
+	Created   float64 `json:"created"`
+	Completed float64 `json:"completed,omitempty"`
+}
+
+type pathJSON struct {
+	Cwd  string `json:"cwd"`
+	Root string `json:"root"`
+}
+
+type tokensJSON struct {
+	Input     float64 `json:"input"`
+	Output    float64 `json:"output"`
+	Reasoning float64 `json:"reasoning"`
+	Cache     struct {
+		Read  float64 `json:"read"`
+		Write float64 `json:"write"`
+	} `json:"cache"`
+}
+
+type partJSON struct {
+	ID        string `json:"id"`
+	MessageID string `json:"messageID"`
+	SessionID string `json:"sessionID"`
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+}
@@ -0,0 +1,232 @@
+package opencode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	probeTimeout      = time.Second
+	readyPollInterval = 100 * time.Millisecond
+	stopGracePeriod   = 5 * time.Second
+)
+
+// ServerConfig says how to run opencode serve
+type ServerConfig struct {
+	Command      string        // "opencode" by default
+	Args         []string      // Extra arguments, e.g. --config opencode.json
+	Dir          string        // Working directory, the current one by default
+	Log          io.Writer     // Output of the server and restarts; discarded when nil
+	ReadyTimeout time.Duration // How long to wait for the server to answer, 15s by default
+}
+
+// Server is an opencode serve child process, restarted when it crashes until
+// Stop is called
+type Server struct {
+	cfg    ServerConfig
+	url    string
+	port   int
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// process is one run of opencode serve
+type process struct {
+	exited chan struct{}
+	err    error
+}
+
+// Probe checks an OpenCode server answers at baseURL
+func Probe(ctx context.Context, baseURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/config", nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", baseURL, res.Status)
+	}
+	return nil
+}
+
+// EnsureServer returns baseURL if an OpenCode server answers there, and
+// otherwise starts one as asked by cfg and returns its URL. srv is nil when
+// the server was already running.
+func EnsureServer(baseURL string, cfg ServerConfig) (url string, srv *Server, err error) {
+	if Probe(context.Background(), baseURL) == nil {
+		return baseURL, nil, nil
+	}
+	srv, err = StartServer(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	return srv.URL(), srv, nil
+}
+
+// StartServer spawns opencode serve on a free local port and waits until it
+// answers. It is restarted on the same port if it exits.
+func StartServer(cfg ServerConfig) (*Server, error) {
+	if cfg.Command == "" {
+		cfg.Command = "opencode"
+	}
+	if cfg.Log == nil {
+		cfg.Log = io.Discard
+	}
+	if cfg.ReadyTimeout == 0 {
+		cfg.ReadyTimeout = 15 * time.Second
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("opencode serve: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		cfg:    cfg,
+		url:    fmt.Sprintf("http://127.0.0.1:%d", port),
+		port:   port,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	p, err := s.spawn()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := s.waitReady(p); err != nil {
+		cancel()
+		<-p.exited
+		return nil, err
+	}
+
+	logger.Info("opencode serve started", "url", s.url)
+	go s.supervise(p)
+	return s, nil
+}
+
+// URL returns the base URL the server listens on
+func (s *Server) URL() string {
+	return s.url
+}
+
+// Stop shuts the server down and waits for it to exit
+func (s *Server) Stop() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// freePort asks the kernel for a local port nothing listens on
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (s *Server) spawn() (*process, error) {
+	args := append([]string{"serve", "--hostname", "127.0.0.1", "--port", strconv.Itoa(s.port)}, s.cfg.Args...)
+	cmd := exec.CommandContext(s.ctx, s.cfg.Command, args...)
+	cmd.Dir = s.cfg.Dir
+	cmd.Stdout = s.cfg.Log
+	cmd.Stderr = s.cfg.Log
+	// Give the server a chance to shut down cleanly before killing it
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = stopGracePeriod
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("opencode serve: %w", err)
+	}
+	p := &process{exited: make(chan struct{})}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.exited)
+	}()
+	return p, nil
+}
+
+// waitReady waits until the server answers, fails or takes too long
+func (s *Server) waitReady(p *process) error {
+	timeout := time.After(s.cfg.ReadyTimeout)
+	tick := time.NewTicker(readyPollInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-p.exited:
+			return fmt.Errorf("opencode serve exited before it was ready: %w", exitErr(p.err))
+		case <-timeout:
+			return fmt.Errorf("opencode serve not ready after %s", s.cfg.ReadyTimeout)
+		case <-tick.C:
+			if Probe(s.ctx, s.url) == nil {
+				return nil
+			}
+		}
+	}
+}
+
+// supervise restarts the server whenever it exits, backing off while it
+// keeps crashing, until Stop
+func (s *Server) supervise(p *process) {
+	defer close(s.done)
+
+	attempt := 0
+	for {
+		started := time.Now()
+		<-p.exited
+		if s.ctx.Err() != nil {
+			return
+		}
+		// A server that ran for a while crashed, it isn't crash looping
+		if time.Since(started) > reconnectMaxDelay {
+			attempt = 0
+		}
+
+		for {
+			delay := backoff(attempt)
+			attempt++
+			fmt.Fprintf(s.cfg.Log, "algopeeps: opencode serve exited (%v), restarting in %s\n", exitErr(p.err), delay.Round(time.Millisecond))
+			logger.Warn("opencode serve exited, restarting", "err", exitErr(p.err), "retry_in", delay)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			next, err := s.spawn()
+			if err == nil {
+				p = next
+				break
+			}
+			p = &process{exited: p.exited, err: err}
+		}
+	}
+}
+
+func exitErr(err error) error {
+	if err == nil {
+		return errors.New("exit status 0")
+	}
+	return err
+}
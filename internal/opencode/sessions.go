@@ -0,0 +1,133 @@
+package opencode
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sst/opencode-sdk-go"
+)
+
+// sessionTitleMarker starts the titles of the sessions algopeeps creates,
+// "Algopeeps Council [project] - title" or "Algopeeps Council - title"
+// without a project. Renaming only changes the part after " - ".
+const sessionTitleMarker = "Algopeeps Council"
+
+// SessionInfo describes a session algopeeps created
+type SessionInfo struct {
+	ID        string
+	Title     string // Without the marker and project
+	Project   string // Project named in the title, or the directory OpenCode serves
+	Directory string
+	Created   time.Time
+	Updated   time.Time
+}
+
+// SetProject names the project new sessions are created for, so they can be
+// told apart later
+func (c *Client) SetProject(name string) {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+	c.project = name
+}
+
+// sessionTitle is the title of a session created now
+func (c *Client) sessionTitle() string {
+	return sessionTitle(c.project, time.Now().Format("2006-01-02"))
+}
+
+// sessionTitle builds the full title of a session for project
+func sessionTitle(project, title string) string {
+	if project == "" {
+		return sessionTitleMarker + " - " + title
+	}
+	return sessionTitleMarker + " [" + project + "] - " + title
+}
+
+// parseSessionTitle splits the full title of a session into its project and
+// title. ok is false for sessions algopeeps didn't create.
+func parseSessionTitle(full string) (project, title string, ok bool) {
+	rest, ok := strings.CutPrefix(full, sessionTitleMarker)
+	if !ok {
+		return "", "", false
+	}
+	if title, ok := strings.CutPrefix(rest, " - "); ok {
+		return "", title, true
+	}
+	if inner, ok := strings.CutPrefix(rest, " ["); ok {
+		return strings.Cut(inner, "] - ")
+	}
+	return "", "", false
+}
+
+// ListSessions returns the sessions algopeeps created, most recently updated
+// first
+func (c *Client) ListSessions() ([]SessionInfo, error) {
+	sessions, err := c.sdk.Session.List(c.ctx, opencode.SessionListParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	var result []SessionInfo
+	for _, s := range *sessions {
+		project, title, ok := parseSessionTitle(s.Title)
+		if !ok || s.ParentID != "" {
+			continue
+		}
+		if project == "" {
+			project = filepath.Base(s.Directory)
+		}
+		result = append(result, SessionInfo{
+			ID:        s.ID,
+			Title:     title,
+			Project:   project,
+			Directory: s.Directory,
+			Created:   time.UnixMilli(int64(s.Time.Created)),
+			Updated:   time.UnixMilli(int64(s.Time.Updated)),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Updated.After(result[j].Updated) })
+	return result, nil
+}
+
+// MessageCount counts the messages in a session. It fetches them all, so
+// ListSessions leaves counting to callers, which can do it in the background.
+func (c *Client) MessageCount(id string) (int, error) {
+	messages, err := c.sdk.Session.Messages(c.ctx, id, opencode.SessionMessagesParams{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list messages: %w", err)
+	}
+	return len(*messages), nil
+}
+
+// RenameSession changes the title of a session of project, as ListSessions
+// reports them. The full title keeps the marker and the project, so the
+// session is listed the same way.
+func (c *Client) RenameSession(id, project, title string) error {
+	_, err := c.sdk.Session.Update(c.ctx, id, opencode.SessionUpdateParams{
+		Title: opencode.F(sessionTitle(project, title)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename session: %w", err)
+	}
+	return nil
+}
+
+// DeleteSession deletes a session. Deleting the current session makes the
+// next EnsureSession create a new one.
+func (c *Client) DeleteSession(id string) error {
+	if _, err := c.sdk.Session.Delete(c.ctx, id, opencode.SessionDeleteParams{}); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+
+	c.sessionMu.Lock()
+	if c.sessionID == id {
+		c.setSession("", false)
+		c.sessionReplaced()
+	}
+	c.sessionMu.Unlock()
+	return nil
+}
@@ -8,6 +8,9 @@ const (
 	EventTextChanged EventType = "text_changed"
 	EventBufferWrite EventType = "buffer_write"
 	EventBufferEnter EventType = "buffer_enter"
+
+	// EventReviewSelection asks for a review focused on Buffer.Selection
+	EventReviewSelection EventType = "review_selection"
 )
 
 type MessageType string
@@ -16,6 +19,10 @@ const (
 	MessageBufferUpdate MessageType = "buffer_update"
 	MessagePing         MessageType = "ping"
 	MessageDisconnect   MessageType = "disconnect"
+	MessageEdit         MessageType = "edit"
+	MessageDiagnostics  MessageType = "diagnostics"
+	MessageQuestion     MessageType = "question"
+	MessageAnswer       MessageType = "answer"
 )
 
 type Cursor struct {
@@ -23,6 +30,18 @@ type Cursor struct {
 	Col  int `json:"col"`
 }
 
+// Position is a location inside a buffer. Lines are 1-based and columns
+// 0-based, matching Cursor.
+type Position struct {
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
 type Buffer struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
@@ -31,6 +50,7 @@ type Buffer struct {
 	Cursor    Cursor `json:"cursor"`
 	LineCount int    `json:"line_count"`
 	Content   string `json:"content"`
+	Selection *Range `json:"selection,omitempty"`
 }
 
 type BufferEvent struct {
@@ -40,6 +60,64 @@ type BufferEvent struct {
 	Buffer    Buffer      `json:"buffer"`
 }
 
+// EditProposal is a replacement suggested by an agent. Edits are line-wise:
+// Text replaces lines Range.Start.Line through Range.End.Line inclusive.
+type EditProposal struct {
+	Path  string `json:"path"`
+	Range Range  `json:"range"`
+	Text  string `json:"text"`
+	Agent string `json:"agent"`
+}
+
+// EditMessage is sent from the server to the editor to apply an EditProposal
+type EditMessage struct {
+	Type      MessageType  `json:"type"`
+	Timestamp time.Time    `json:"timestamp"`
+	Edit      EditProposal `json:"edit"`
+}
+
+// Diagnostic is an agent finding in the shape editors display
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity string `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// DiagnosticsMessage replaces every algopeeps diagnostic the editor shows for
+// Path. An empty Diagnostics list clears them.
+type DiagnosticsMessage struct {
+	Type        MessageType  `json:"type"`
+	Timestamp   time.Time    `json:"timestamp"`
+	Path        string       `json:"path"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// QuestionMessage is a free-form question the editor asks the council about
+// a buffer, optionally scoped to a selection
+type QuestionMessage struct {
+	Type      MessageType `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	ID        string      `json:"id"`
+	Agent     string      `json:"agent,omitempty"` // Empty asks every agent
+	Question  string      `json:"question"`
+	Selection *Range      `json:"selection,omitempty"`
+	Buffer    Buffer      `json:"buffer"`
+}
+
+// AnswerMessage streams an agent's answer to a QuestionMessage back to the
+// editor. Until Done, Text is the next chunk of the answer; the Done message
+// carries the complete answer.
+type AnswerMessage struct {
+	Type      MessageType `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	ID        string      `json:"id"`
+	Agent     string      `json:"agent"`
+	Text      string      `json:"text"`
+	Done      bool        `json:"done"`
+	Error     string      `json:"error,omitempty"`
+}
+
 func (e *BufferEvent) Validate() error {
 	return nil
 }
@@ -0,0 +1,96 @@
+// Package replay records the JSON-lines stream editors send the TUI and plays
+// recordings back, into a running TUI or straight to the council
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Entry is one line an editor sent, as recorded
+type Entry struct {
+	Time time.Time       `json:"time"`
+	Conn int             `json:"conn"` // Editor connection the line came on, numbered from 1
+	Line json.RawMessage `json:"line"`
+}
+
+// Recorder appends the lines editors send to a recording
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder records to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Record appends line, received on connection conn. Lines that aren't JSON
+// are left out: the server ignores them too.
+func (r *Recorder) Record(conn int, line []byte) {
+	if !json.Valid(line) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.err = r.enc.Encode(Entry{Time: time.Now(), Conn: conn, Line: json.RawMessage(line)})
+}
+
+// Err returns the first error writing the recording
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Read loads a recording
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("recording line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("recording: %w", err)
+	}
+	return entries, nil
+}
+
+// Play calls fn with each entry at the pace they were recorded at, sped up
+// by speed; a speed of 0 plays them back to back. When fn takes longer than
+// the gap to the next entry, the next one follows right away.
+func Play(entries []Entry, speed float64, fn func(Entry) error) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	start := time.Now()
+	first := entries[0].Time
+	for _, e := range entries {
+		if speed > 0 {
+			at := time.Duration(float64(e.Time.Sub(first)) / speed)
+			if wait := at - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
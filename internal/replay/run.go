@@ -0,0 +1,183 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// Result is what the council made of a recording
+type Result struct {
+	Events    int                // Buffer events reviewed
+	Questions int                // Questions answered
+	Findings  []findings.Finding // Those standing at the end, sorted by path and line
+	Errors    []error            // Agents that failed to review or answer
+}
+
+// Run plays entries to the council without a TUI, like the dashboard would
+// handle them: every buffer event is reviewed by all agents and every
+// question is put to the agent it names. Each event and its outcome is
+// written to out when it isn't nil.
+func Run(p council.Prompter, entries []Entry, speed float64, out io.Writer) (Result, error) {
+	if out == nil {
+		out = io.Discard
+	}
+
+	var result Result
+	store := findings.NewStore()
+	var first time.Time
+	if len(entries) > 0 {
+		first = entries[0].Time
+	}
+
+	err := Play(entries, speed, func(e Entry) error {
+		var header struct {
+			Type protocol.MessageType `json:"type"`
+		}
+		if err := json.Unmarshal(e.Line, &header); err != nil {
+			return nil
+		}
+		at := e.Time.Sub(first).Round(100 * time.Millisecond)
+
+		switch header.Type {
+		case protocol.MessageBufferUpdate:
+			var event protocol.BufferEvent
+			if err := json.Unmarshal(e.Line, &event); err != nil {
+				return nil
+			}
+			fmt.Fprintf(out, "+%s %s %s\n", at, event.Event, bufferName(event.Buffer))
+			review(p, event, store, &result, out)
+		case protocol.MessageQuestion:
+			var question protocol.QuestionMessage
+			if err := json.Unmarshal(e.Line, &question); err != nil {
+				return nil
+			}
+			fmt.Fprintf(out, "+%s question %q\n", at, question.Question)
+			ask(p, question, &result, out)
+		}
+		return nil
+	})
+
+	result.Findings = store.All()
+	return result, err
+}
+
+// review puts a buffer event in front of the council and keeps the findings
+// standing for the buffer, as the dashboard does
+func review(p council.Prompter, event protocol.BufferEvent, store *findings.Store, result *Result, out io.Writer) {
+	path := event.Buffer.Path
+	if path == "" {
+		path = event.Buffer.Name
+	}
+	req := council.Request{
+		Path:       path,
+		Filename:   event.Buffer.Name,
+		Filetype:   event.Buffer.Filetype,
+		CursorLine: event.Buffer.Cursor.Line,
+		CursorCol:  event.Buffer.Cursor.Col,
+		Event:      string(event.Event),
+		Content:    event.Buffer.Content,
+		Selection:  event.Buffer.Selection,
+	}
+
+	result.Events++
+	for _, res := range council.Review(p, req) {
+		switch {
+		case res.Err != nil:
+			result.Errors = append(result.Errors, fmt.Errorf("%s on %s: %w", res.Agent, bufferName(event.Buffer), res.Err))
+			fmt.Fprintf(out, "  %-14s failed: %v\n", res.Agent, res.Err)
+		case res.Text == "":
+			// No prompt for this event
+		default:
+			fs := findings.Parse(res.Agent, path, res.Text, event.Buffer.Content)
+			store.Replace(path, res.Agent, fs)
+			summary := findings.Summary(fs)
+			if summary == "" {
+				summary = "no findings"
+			}
+			fmt.Fprintf(out, "  %-14s %s\n", res.Agent, summary)
+		}
+	}
+}
+
+// ask puts a question to the agent it names, or to every agent
+func ask(p council.Prompter, question protocol.QuestionMessage, result *Result, out io.Writer) {
+	agents := council.Agents
+	if question.Agent != "" {
+		if !slices.Contains(council.Agents, question.Agent) {
+			result.Errors = append(result.Errors, fmt.Errorf("question for unknown agent @%s", question.Agent))
+			fmt.Fprintf(out, "  unknown agent @%s\n", question.Agent)
+			return
+		}
+		agents = []string{question.Agent}
+	}
+	if question.Question == "" {
+		return
+	}
+
+	req := council.Request{
+		Path:       question.Buffer.Path,
+		Filename:   question.Buffer.Name,
+		Filetype:   question.Buffer.Filetype,
+		CursorLine: question.Buffer.Cursor.Line,
+		CursorCol:  question.Buffer.Cursor.Col,
+		Content:    question.Buffer.Content,
+		Selection:  question.Selection,
+	}
+
+	result.Questions++
+	prompts, _ := council.QuestionPrompts(agents, req, question.Question)
+	for _, prompt := range prompts {
+		agent := prompt.Agent
+		text, err := p.Prompt(agent, prompt.Prompt)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("%s answering %q: %w", agent, question.Question, err))
+			fmt.Fprintf(out, "  %-14s failed: %v\n", agent, err)
+			continue
+		}
+		fmt.Fprintf(out, "  %-14s %s\n", agent, strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n                 "))
+	}
+}
+
+func bufferName(b protocol.Buffer) string {
+	if b.Name != "" {
+		return b.Name
+	}
+	return b.Path
+}
+
+// WriteReport prints the findings standing at the end of the replay grouped
+// by file, followed by a summary
+func (r Result) WriteReport(w io.Writer) {
+	var path string
+	for _, f := range r.Findings {
+		if f.Path != path {
+			if path != "" {
+				fmt.Fprintln(w)
+			}
+			path = f.Path
+			fmt.Fprintln(w, path)
+		}
+		fmt.Fprintf(w, "  L%-5d %-8s %-14s %s\n", f.Range.Start.Line, f.Severity, f.Agent, f.Message)
+	}
+	if len(r.Findings) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	summary := fmt.Sprintf("%d findings after %d buffer events and %d questions", len(r.Findings), r.Events, r.Questions)
+	if counts := findings.Summary(r.Findings); counts != "" {
+		summary += " (" + counts + ")"
+	}
+	fmt.Fprintln(w, summary)
+
+	for _, err := range r.Errors {
+		fmt.Fprintf(w, "warning: %v\n", err)
+	}
+}
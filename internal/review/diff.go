@@ -0,0 +1,103 @@
+package review
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// Hunk is one changed region of a file in a unified diff
+type Hunk struct {
+	Path     string // Path in the new version, relative to the repository root
+	NewStart int    // First line of the hunk in the new version
+	NewLines int    // Lines the hunk spans in the new version, context included
+	Text     string // The hunk, header line included
+
+	// Changed covers the added lines, or the line following removed ones
+	// when nothing was added
+	Changed protocol.Range
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// ParseDiff splits the output of git diff into hunks. Deleted and binary
+// files have no hunks to review and are skipped.
+func ParseDiff(diff string) []Hunk {
+	var hunks []Hunk
+	var path string
+	var current *Hunk
+	var body strings.Builder
+	newLine, firstAdded, lastAdded, removedAt := 0, 0, 0, 0
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		current.Text = strings.TrimRight(body.String(), "\n")
+		start, end := firstAdded, lastAdded
+		if start == 0 {
+			// Only removals: point at the line that now follows them
+			start = max(removedAt, 1)
+			end = start
+		}
+		current.Changed = protocol.Range{
+			Start: protocol.Position{Line: start},
+			End:   protocol.Position{Line: end},
+		}
+		hunks = append(hunks, *current)
+		current = nil
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			path = ""
+		case current == nil && strings.HasPrefix(line, "+++ "):
+			path = strings.TrimPrefix(strings.TrimPrefix(line, "+++ "), "b/")
+			if path == "/dev/null" {
+				path = ""
+			}
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			match := hunkHeaderRe.FindStringSubmatch(line)
+			if match == nil || path == "" {
+				continue
+			}
+			start, _ := strconv.Atoi(match[1])
+			count := 1
+			if match[2] != "" {
+				count, _ = strconv.Atoi(match[2])
+			}
+			current = &Hunk{Path: path, NewStart: start, NewLines: count}
+			body.Reset()
+			body.WriteString(line + "\n")
+			newLine, firstAdded, lastAdded, removedAt = start, 0, 0, 0
+			if count == 0 {
+				// git numbers an empty new side from the line before it
+				newLine = start + 1
+			}
+		case current != nil:
+			body.WriteString(line + "\n")
+			switch {
+			case strings.HasPrefix(line, "+"):
+				if firstAdded == 0 {
+					firstAdded = newLine
+				}
+				lastAdded = newLine
+				newLine++
+			case strings.HasPrefix(line, "-"):
+				if removedAt == 0 {
+					removedAt = newLine
+				}
+			case strings.HasPrefix(line, " "):
+				newLine++
+			}
+		}
+	}
+	flush()
+
+	return hunks
+}
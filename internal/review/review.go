@@ -0,0 +1,213 @@
+// Package review puts the changes in a git repository in front of the council
+// without an editor, e.g. from a pre-commit hook
+package review
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+)
+
+// Source is a set of changes in a git repository
+type Source struct {
+	Root string // Repository root
+
+	diffArgs []string
+	rev      string // Revision holding the new version of files
+	index    bool   // New versions are staged in the index
+	worktree bool   // New versions are in the working tree
+}
+
+// Staged returns the changes staged for the next commit in the repository
+// holding dir
+func Staged(dir string) (Source, error) {
+	root, err := repoRoot(dir)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{Root: root, diffArgs: []string{"--cached"}, index: true}, nil
+}
+
+// Range returns the changes described by spec, as git diff takes it:
+// "A..B" or "A...B" compare two commits, a single revision compares it to
+// the working tree
+func Range(dir, spec string) (Source, error) {
+	root, err := repoRoot(dir)
+	if err != nil {
+		return Source{}, err
+	}
+
+	src := Source{Root: root, diffArgs: []string{spec}}
+	for _, sep := range []string{"...", ".."} {
+		if _, rev, ok := strings.Cut(spec, sep); ok {
+			src.rev = rev
+			if src.rev == "" {
+				src.rev = "HEAD"
+			}
+			return src, nil
+		}
+	}
+	src.worktree = true
+	return src, nil
+}
+
+// Diff returns the changes as a unified diff
+func (s Source) Diff() (string, error) {
+	args := append([]string{"diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/"}, s.diffArgs...)
+	return git(s.Root, args...)
+}
+
+// Content returns the new version of the file at path, relative to Root
+func (s Source) Content(path string) (string, error) {
+	switch {
+	case s.worktree:
+		data, err := os.ReadFile(filepath.Join(s.Root, filepath.FromSlash(path)))
+		return string(data), err
+	case s.index:
+		return git(s.Root, "show", ":"+path)
+	default:
+		return git(s.Root, "show", s.rev+":"+path)
+	}
+}
+
+// Result is what the council found in a set of changes
+type Result struct {
+	Findings []findings.Finding // Sorted by path and line
+	Units    int                // Hunks or chunks reviewed
+	Files    int
+	Errors   []error // Units an agent failed to review
+
+	unitName string // What a unit is, plural
+}
+
+// Run sends every hunk of src to the council and collects the findings that
+// fall inside the hunks. Progress is written to progress when it isn't nil.
+func Run(p council.Prompter, src Source, progress io.Writer) (Result, error) {
+	diff, err := src.Diff()
+	if err != nil {
+		return Result{}, err
+	}
+	hunks := ParseDiff(diff)
+
+	result := Result{unitName: "hunks"}
+	contents := make(map[string]string)
+	for i, hunk := range hunks {
+		content, ok := contents[hunk.Path]
+		if !ok {
+			content, err = src.Content(hunk.Path)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("%s: %w", hunk.Path, err))
+				continue
+			}
+			contents[hunk.Path] = content
+		}
+
+		if progress != nil {
+			fmt.Fprintf(progress, "Reviewing %s:%d (%d/%d)\n", hunk.Path, hunk.Changed.Start.Line, i+1, len(hunks))
+		}
+		changed := hunk.Changed
+		req := council.Request{
+			Path:       filepath.Join(src.Root, filepath.FromSlash(hunk.Path)),
+			Filename:   hunk.Path,
+			Filetype:   strings.TrimPrefix(filepath.Ext(hunk.Path), "."),
+			CursorLine: changed.Start.Line,
+			Event:      council.HunkEvent,
+			Content:    content,
+			Selection:  &changed,
+			Diff:       hunk.Text,
+		}
+
+		for _, res := range council.Review(p, req) {
+			if res.Err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("%s on %s:%d: %w", res.Agent, hunk.Path, changed.Start.Line, res.Err))
+				continue
+			}
+			for _, f := range findings.Parse(res.Agent, hunk.Path, res.Text, content) {
+				if inHunk(f, hunk) {
+					result.Findings = append(result.Findings, f)
+				}
+			}
+		}
+		result.Units++
+	}
+
+	result.Files = len(contents)
+	result.sort()
+	return result, nil
+}
+
+func (r *Result) sort() {
+	findings.SortByLocation(r.Findings)
+}
+
+// inHunk reports whether a finding is about the lines a hunk spans. Agents
+// see more of the file than the hunk and tend to comment on all of it.
+func inHunk(f findings.Finding, h Hunk) bool {
+	end := max(h.NewStart+h.NewLines-1, h.Changed.End.Line)
+	return f.Range.End.Line >= h.NewStart && f.Range.Start.Line <= end
+}
+
+// Failed reports whether any finding is at least as severe as threshold
+func (r Result) Failed(threshold findings.Severity) bool {
+	for _, f := range r.Findings {
+		if f.Severity.AtLeast(threshold) {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport prints the findings grouped by file, followed by a summary
+func (r Result) WriteReport(w io.Writer) {
+	var path string
+	for _, f := range r.Findings {
+		if f.Path != path {
+			if path != "" {
+				fmt.Fprintln(w)
+			}
+			path = f.Path
+			fmt.Fprintln(w, path)
+		}
+		fmt.Fprintf(w, "  L%-5d %-8s %-14s %s\n", f.Range.Start.Line, f.Severity, f.Agent, f.Message)
+	}
+	if len(r.Findings) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	summary := fmt.Sprintf("%d findings in %d %s across %d files", len(r.Findings), r.Units, r.unitName, r.Files)
+	if counts := findings.Summary(r.Findings); counts != "" {
+		summary += " (" + counts + ")"
+	}
+	fmt.Fprintln(w, summary)
+
+	for _, err := range r.Errors {
+		fmt.Fprintf(w, "warning: %v\n", err)
+	}
+}
+
+func repoRoot(dir string) (string, error) {
+	root, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(root), nil
+}
+
+// git runs a git command in dir and returns its stdout
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
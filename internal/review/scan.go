@@ -0,0 +1,176 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
+// generatedRe matches the comment marking generated Go files
+var generatedRe = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// GoFiles expands patterns into Go source files the way the go command
+// expands packages: "dir/..." is every package under dir, a directory is the
+// package in it and a file is itself. Hidden directories, vendor and testdata
+// are skipped, and so are test files unless tests is set.
+func GoFiles(patterns []string, tests bool) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	wanted := func(name string) bool {
+		return strings.HasSuffix(name, ".go") && (tests || !strings.HasSuffix(name, "_test.go"))
+	}
+
+	for _, pattern := range patterns {
+		root, recursive := strings.CutSuffix(pattern, "...")
+		root = filepath.Clean(strings.TrimSuffix(root, "/"))
+		if root == "" {
+			root = "."
+		}
+
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			add(root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				name := d.Name()
+				if path != root && (!recursive || strings.HasPrefix(name, ".") || name == "vendor" || name == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if wanted(d.Name()) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// chunk is a part of a file reviewed in one prompt per agent
+type chunk struct {
+	path    string
+	content string
+	lines   protocol.Range
+}
+
+// Scan reviews files whole, cutting the ones too large for a prompt into
+// chunks along declarations, see council.Chunks. Generated files are skipped.
+// Each of prompters reviews one chunk at a time, sending it to every agent,
+// so give each its own session. Progress is written to progress when it
+// isn't nil.
+func Scan(prompters []council.Prompter, files []string, progress io.Writer) (Result, error) {
+	if len(prompters) == 0 {
+		return Result{}, errors.New("scan: no prompters")
+	}
+	result := Result{unitName: "chunks"}
+
+	var chunks []chunk
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Result{}, err
+		}
+		content := string(data)
+		if generatedRe.MatchString(content) {
+			continue
+		}
+		result.Files++
+		for _, r := range council.Chunks(content, "go", path) {
+			chunks = append(chunks, chunk{path: path, content: content, lines: r})
+		}
+	}
+
+	var mu sync.Mutex
+	done := 0
+	work := make(chan chunk)
+	var wg sync.WaitGroup
+	for _, p := range prompters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range work {
+				fs, errs := scanChunk(p, c)
+
+				mu.Lock()
+				result.Findings = append(result.Findings, fs...)
+				result.Errors = append(result.Errors, errs...)
+				result.Units++
+				done++
+				if progress != nil {
+					fmt.Fprintf(progress, "Reviewed %s:%d-%d (%d/%d)\n", c.path, c.lines.Start.Line, c.lines.End.Line, done, len(chunks))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, c := range chunks {
+		work <- c
+	}
+	close(work)
+	wg.Wait()
+
+	result.sort()
+	return result, nil
+}
+
+// scanChunk sends one chunk to the council and keeps the findings inside it
+func scanChunk(p council.Prompter, c chunk) ([]findings.Finding, []error) {
+	path := c.path
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	lines := c.lines
+	req := council.Request{
+		Path:       path,
+		Filename:   c.path,
+		Filetype:   "go",
+		CursorLine: lines.Start.Line,
+		Event:      council.ScanEvent,
+		Content:    c.content,
+		Selection:  &lines,
+	}
+
+	var result []findings.Finding
+	var errs []error
+	for _, res := range council.Review(p, req) {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("%s on %s:%d: %w", res.Agent, c.path, lines.Start.Line, res.Err))
+			continue
+		}
+		for _, f := range findings.Parse(res.Agent, c.path, res.Text, c.content) {
+			if f.Range.End.Line >= lines.Start.Line && f.Range.Start.Line <= lines.End.Line {
+				result = append(result, f)
+			}
+		}
+	}
+	return result, errs
+}
@@ -3,28 +3,37 @@ package server
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
+	"sync/atomic"
 
+	"github.com/abhirupda/algopeeps/internal/logging"
 	"github.com/abhirupda/algopeeps/internal/protocol"
+	"github.com/abhirupda/algopeeps/internal/replay"
 	"github.com/abhirupda/algopeeps/internal/tui"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+var logger = logging.Component("server")
+
 // Server handles TCP connections from Neovim
 type Server struct {
 	addr     string
 	listener net.Listener
 	program  *tea.Program
 	mu       sync.Mutex
-	clients  []net.Conn
-	running  bool
+	clients  map[int]net.Conn // By connection number
+	running  atomic.Bool
+	recorder *replay.Recorder
+	conns    int // Connections accepted so far, numbering them
 }
 
 // New creates a new TCP server
 func New(addr string) *Server {
-	return &Server{addr: addr}
+	return &Server{addr: addr, clients: make(map[int]net.Conn)}
 }
 
 // SetProgram sets the Bubble Tea program for message injection
@@ -32,6 +41,11 @@ func (s *Server) SetProgram(p *tea.Program) {
 	s.program = p
 }
 
+// SetRecorder records every line editors send to r, see replay.Recorder
+func (s *Server) SetRecorder(r *replay.Recorder) {
+	s.recorder = r
+}
+
 // Start starts the TCP server
 func (s *Server) Start() error {
 	var err error
@@ -39,7 +53,8 @@ func (s *Server) Start() error {
 	if err != nil {
 		return fmt.Errorf("failed to start server: %w", err)
 	}
-	s.running = true
+	s.running.Store(true)
+	logger.Info("listening", "addr", s.listener.Addr())
 
 	go s.acceptLoop()
 	return nil
@@ -55,39 +70,90 @@ func (s *Server) Addr() string {
 
 // Stop stops the server
 func (s *Server) Stop() error {
-	s.running = false
+	s.running.Store(false)
 	if s.listener != nil {
 		return s.listener.Close()
 	}
 	return nil
 }
 
+// Broadcast sends msg as a JSON line to every connected client
+func (s *Server) Broadcast(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.clients) == 0 {
+		return fmt.Errorf("no editor connected")
+	}
+
+	var firstErr error
+	for _, conn := range s.clients {
+		if _, err := conn.Write(data); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to write to %s: %w", conn.RemoteAddr(), err)
+		}
+	}
+	return firstErr
+}
+
+// Send sends msg as a JSON line to the client on connection id, as numbered
+// in the messages it sent
+func (s *Server) Send(id int, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conn, ok := s.clients[id]
+	if !ok {
+		return fmt.Errorf("editor %d disconnected", id)
+	}
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("failed to write to %s: %w", conn.RemoteAddr(), err)
+	}
+	return nil
+}
+
 func (s *Server) acceptLoop() {
-	for s.running {
+	for s.running.Load() {
 		conn, err := s.listener.Accept()
 		if err != nil {
-			if s.running {
-				// Log error but continue
+			// Errors while running are transient, keep accepting
+			if s.running.Load() {
+				logger.Warn("accepting connection", "err", err)
 			}
 			continue
 		}
 		s.mu.Lock()
-		s.clients = append(s.clients, conn)
+		s.conns++
+		id := s.conns
+		s.clients[id] = conn
 		s.mu.Unlock()
+		logger.Info("editor connected", "conn", id, "remote", conn.RemoteAddr())
 
 		// Notify TUI of new connection
 		if s.program != nil {
 			s.program.Send(tui.ConnectionStatusMsg{Connected: true, Source: "nvim"})
 		}
 
-		go s.handleConnection(conn)
+		go s.handleConnection(conn, id)
 	}
 }
 
-func (s *Server) handleConnection(conn net.Conn) {
+func (s *Server) handleConnection(conn net.Conn, id int) {
 	defer func() {
 		conn.Close()
-		s.removeClient(conn)
+		s.removeClient(id)
+		logger.Info("editor disconnected", "conn", id)
 		if s.program != nil {
 			s.program.Send(tui.ConnectionStatusMsg{Connected: false, Source: "nvim"})
 		}
@@ -97,35 +163,77 @@ func (s *Server) handleConnection(conn net.Conn) {
 	for {
 		line, err := reader.ReadBytes('\n')
 		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Warn("reading from editor", "conn", id, "err", err)
+			}
 			return
 		}
+		if s.recorder != nil {
+			s.recorder.Record(id, line)
+		}
 
-		var event protocol.BufferEvent
-		if err := json.Unmarshal(line, &event); err != nil {
+		var header struct {
+			Type protocol.MessageType `json:"type"`
+		}
+		if err := json.Unmarshal(line, &header); err != nil {
+			logger.Warn("ignoring malformed message", "conn", id, "err", err)
 			continue
 		}
 
-		if s.program != nil {
-			s.program.Send(tui.BufferEventMsg{
-				Filename:   event.Buffer.Name,
-				Filetype:   event.Buffer.Filetype,
-				CursorLine: event.Buffer.Cursor.Line,
-				CursorCol:  event.Buffer.Cursor.Col,
-				LineCount:  event.Buffer.LineCount,
-				LastEvent:  string(event.Event),
-				Content:    event.Buffer.Content,
-			})
+		switch header.Type {
+		case protocol.MessageQuestion:
+			s.handleQuestion(line, id)
+		default:
+			s.handleBufferEvent(line)
 		}
 	}
 }
 
-func (s *Server) removeClient(conn net.Conn) {
+func (s *Server) handleBufferEvent(line []byte) {
+	var event protocol.BufferEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		logger.Warn("ignoring malformed buffer event", "err", err)
+		return
+	}
+	logger.Debug("buffer event", "event", event.Event, "path", event.Buffer.Path)
+
+	if s.program != nil {
+		s.program.Send(tui.BufferEventMsg{
+			Filename:   event.Buffer.Name,
+			Path:       event.Buffer.Path,
+			Filetype:   event.Buffer.Filetype,
+			CursorLine: event.Buffer.Cursor.Line,
+			CursorCol:  event.Buffer.Cursor.Col,
+			LineCount:  event.Buffer.LineCount,
+			LastEvent:  string(event.Event),
+			Content:    event.Buffer.Content,
+			Selection:  event.Buffer.Selection,
+		})
+	}
+}
+
+func (s *Server) handleQuestion(line []byte, conn int) {
+	var question protocol.QuestionMessage
+	if err := json.Unmarshal(line, &question); err != nil {
+		logger.Warn("ignoring malformed question", "err", err)
+		return
+	}
+	logger.Debug("question", "id", question.ID, "agent", question.Agent)
+
+	if s.program != nil {
+		s.program.Send(tui.QuestionMsg{
+			ID:        question.ID,
+			Conn:      conn,
+			Agent:     question.Agent,
+			Question:  question.Question,
+			Selection: question.Selection,
+			Buffer:    question.Buffer,
+		})
+	}
+}
+
+func (s *Server) removeClient(id int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	for i, c := range s.clients {
-		if c == conn {
-			s.clients = append(s.clients[:i], s.clients[i+1:]...)
-			break
-		}
-	}
+	delete(s.clients, id)
 }
@@ -2,9 +2,16 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
-
+	"slices"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/backend"
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/history"
+	"github.com/abhirupda/algopeeps/internal/logging"
 	"github.com/abhirupda/algopeeps/internal/opencode"
 	"github.com/abhirupda/algopeeps/internal/tui/components"
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,49 +24,99 @@ type Model struct {
 	ready             bool
 	agents            map[string]string
 	agentThinking     map[string]bool
+	replied           map[string]bool // Agents whose full reply arrived; deltas straggling in after it are dropped
 	nvimConnected     bool
 	openCodeConnected bool
+	openCodeState     opencode.State
 	ocClient          *opencode.Client
+	backends          *backend.Router
 	bufferFilename    string
+	bufferPath        string
+	bufferContent     string
 	bufferFiletype    string
 	bufferLine        int
 	bufferCol         int
 	bufferLines       int
 	lastEvent         string
 	lastError         string
+	pendingEdits      []pendingEdit
+	sendingEdit       bool // The first pending edit is on its way to the editor
+	findings          *findings.Store
+	input             string
+	inputActive       bool
+	questions         map[string]editorQuestion // By the agent answering it
+	editor            EditorSink
+	reportsDir        string
+	history           *history.Store
+	browser           sessionBrowser
+	notice            string
+	log               *logging.Recent
+	logPath           string
+	logOpen           bool
+	logSeen           int // Entries logged when the log pane was last looked at
 }
 
-func NewModel() Model {
+// NewModel creates the dashboard, talking to the OpenCode server in cfg
+func NewModel(cfg opencode.Config) Model {
 	// Create OpenCode client
-	client, err := opencode.NewClient(opencode.DefaultConfig())
+	client, err := opencode.NewClient(cfg)
 	if err != nil {
-		// Log error but continue - we can try to reconnect later
+		// Carry on without agents; the status bar shows OpenCode down
+		logger.Error("creating OpenCode client", "url", cfg.BaseURL, "err", err)
 		client = nil
 	}
 
+	var backends *backend.Router
+	if client != nil {
+		if backends, err = backend.NewRouter(backend.Config{}, client); err != nil {
+			logger.Error("routing agents to OpenCode", "err", err)
+		}
+	}
+
 	return Model{
 		agents:        make(map[string]string),
 		agentThinking: make(map[string]bool),
+		replied:       make(map[string]bool),
 		ocClient:      client,
+		backends:      backends,
+		findings:      findings.NewStore(),
+		questions:     make(map[string]editorQuestion),
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	if m.ocClient != nil {
-		return func() tea.Msg {
-			if err := m.ocClient.EnsureSession(); err != nil {
-				return ErrorMsg{Error: err, Context: "OpenCode session initialization"}
-			}
-			return ConnectionStatusMsg{Connected: true, Source: "opencode"}
-		}
+	return nil
+}
+
+// SetAgentBackends runs the agents cfg configures on their own backend
+// rather than OpenCode
+func (m *Model) SetAgentBackends(cfg backend.Config) error {
+	if m.ocClient == nil {
+		return nil
 	}
+	backends, err := backend.NewRouter(cfg, m.ocClient)
+	if err != nil {
+		return err
+	}
+	m.backends = backends
 	return nil
 }
 
+// StartSSESubscription streams agent output to p and, when agents run on
+// OpenCode, starts the OpenCode client lifecycle, which creates the session
 func (m *Model) StartSSESubscription(p *tea.Program) {
-	if m.ocClient != nil {
+	if m.backends == nil {
+		return
+	}
+	m.backends.OnDelta(func(agent, text string) {
+		p.Send(opencode.AgentTextMsg{Agent: agent, Text: text})
+	})
+	if m.backends.Uses(m.ocClient) {
 		go func() {
-			_ = m.ocClient.SubscribeEvents(context.Background(), p)
+			err := m.ocClient.SubscribeEvents(context.Background(), p)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				logger.Error("OpenCode lifecycle stopped", "err", err)
+			}
 		}()
 	}
 }
@@ -67,12 +124,32 @@ func (m *Model) StartSSESubscription(p *tea.Program) {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.inputActive && msg.String() != "ctrl+c" {
+			return m, m.handleInputKey(msg)
+		}
+		if m.browser.open && msg.String() != "ctrl+c" {
+			return m, m.handleBrowserKey(msg)
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
-			if m.ocClient != nil {
-				_ = m.ocClient.Close()
+			if m.backends != nil {
+				if err := m.backends.Close(); err != nil {
+					logger.Warn("closing agent backends", "err", err)
+				}
 			}
 			return m, tea.Quit
+		case "a":
+			return m, m.applyEdit()
+		case "d":
+			m.discardEdit()
+		case "e":
+			return m, m.exportFindings()
+		case "s":
+			return m, m.openSessions()
+		case "l":
+			m.toggleLog()
+		case "/":
+			m.inputActive = true
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
@@ -82,126 +159,198 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg.Source {
 		case "nvim":
 			m.nvimConnected = msg.Connected
-		case "opencode":
-			m.openCodeConnected = msg.Connected
 		}
 	case ErrorMsg:
+		logger.Error(msg.Context, "err", msg.Error)
 		m.lastError = fmt.Sprintf("%s: %v", msg.Context, msg.Error)
+	case LogMsg:
+		if m.logOpen {
+			m.logSeen = m.log.Total()
+		}
+	case SessionsMsg:
+		return m, m.handleSessionsMsg(msg)
+	case sessionMessagesMsg:
+		m.handleSessionMessages(msg)
+	case ExportedMsg:
+		m.lastError = ""
+		m.notice = fmt.Sprintf("Exported %d findings to %s", msg.Count, msg.Path)
 	case opencode.AgentTextMsg:
+		if m.replied[msg.Agent] {
+			return m, nil
+		}
 		if m.agents == nil {
 			m.agents = make(map[string]string)
 		}
 		m.agents[msg.Agent] += msg.Text
 		m.agentThinking[msg.Agent] = false
+		return m, m.streamAnswer(msg.Agent, msg.Text)
+	case opencode.StateMsg:
+		m.openCodeState = msg.State
+		m.openCodeConnected = msg.State == opencode.StateStreaming
+		switch {
+		case msg.State == opencode.StateSessionReady:
+			return m, m.saveSession()
+		case msg.State == opencode.StateStreaming:
+			m.lastError = ""
+		case msg.Err != nil:
+			m.lastError = fmt.Sprintf("OpenCode %v, retrying in %s", msg.Err, msg.RetryIn.Round(100*time.Millisecond))
+		}
 	case opencode.AgentIdleMsg:
 		m.agentThinking[msg.Agent] = false
+	case QuestionMsg:
+		return m, m.handleQuestion(msg)
+	case ChatReplyMsg:
+		return m, m.handleChatReply(msg)
+	case editSentMsg:
+		m.handleEditSent(msg)
+	case reviewPromptsMsg:
+		return m, m.handleReviewPrompts(msg)
+	case questionPromptsMsg:
+		return m, m.handleQuestionPrompts(msg)
+	case AgentResponseMsg:
+		m.agents[msg.Agent] = msg.Text
+		m.agentThinking[msg.Agent] = false
+		m.replied[msg.Agent] = true
+		m.replaceEdits(msg.Agent, msg.Path, msg.Content, findings.ParseEdits(msg.Agent, msg.Path, msg.Text))
+		fs := findings.Parse(msg.Agent, msg.Path, msg.Text, msg.Content)
+		m.findings.Replace(msg.Path, msg.Agent, fs)
+		return m, tea.Batch(m.publishDiagnostics(msg.Path), m.saveResponse(msg.Agent, msg.Path, msg.Text, fs))
 	case BufferEventMsg:
 		m.bufferFilename = msg.Filename
+		m.bufferPath = msg.Path
+		if m.bufferPath == "" {
+			m.bufferPath = msg.Filename
+		}
+		m.bufferContent = msg.Content
 		m.bufferFiletype = msg.Filetype
 		m.bufferLine = msg.CursorLine
 		m.bufferCol = msg.CursorCol
 		m.bufferLines = msg.LineCount
 		m.lastEvent = msg.LastEvent
 
+		// Findings and proposed edits follow their lines through the edit, or
+		// go with them
+		m.followEdits(m.bufferPath, msg.Content)
+		var cmds []tea.Cmd
+		if m.findings.Prune(m.bufferPath, msg.Content) {
+			cmds = append(cmds, m.publishDiagnostics(m.bufferPath), m.saveFindings(m.bufferPath))
+		}
 		if m.ocClient != nil {
-			go m.handleBufferEvent(msg)
+			cmds = append(cmds, m.handleBufferEvent(msg))
 		}
+		return m, tea.Batch(cmds...)
 	}
 	return m, nil
 }
 
 // handleBufferEvent processes buffer events and sends prompts to agents
-func (m *Model) handleBufferEvent(msg BufferEventMsg) {
-	// Ensure session exists
-	if err := m.ocClient.EnsureSession(); err != nil {
-		return
+func (m *Model) handleBufferEvent(msg BufferEventMsg) tea.Cmd {
+	req := council.Request{
+		Path:       m.bufferPath,
+		Filename:   msg.Filename,
+		Filetype:   msg.Filetype,
+		CursorLine: msg.CursorLine,
+		CursorCol:  msg.CursorCol,
+		Event:      msg.LastEvent,
+		Content:    msg.Content,
+		Selection:  msg.Selection,
 	}
 
-	// Truncate content if needed (>100KB, keep 50 lines around cursor)
-	content := msg.Content
-	const maxSize = 100 * 1024 // 100KB
-
-	if len(content) > maxSize {
-		content = m.truncateAroundCursor(content, msg.CursorLine, 50)
+	for _, agent := range council.Agents {
+		m.agentThinking[agent] = true
+		m.agents[agent] = ""
+		delete(m.replied, agent)
+		// The review streams in the agent's card, not into an open answer;
+		// the question still gets its reply once complete
+		delete(m.questions, agent)
 	}
 
-	// Construct prompt using the template
-	prompt := m.buildPrompt(msg.Filename, msg.Filetype, msg.CursorLine, msg.CursorCol, msg.LastEvent, content)
-
-	// Set agents to thinking state
-	m.agentThinking["code-reviewer"] = true
-	m.agentThinking["bug-spotter"] = true
-
-	// Send to both agents
-	go func() {
-		_ = m.ocClient.SendPrompt("code-reviewer", prompt)
-	}()
-	go func() {
-		_ = m.ocClient.SendPrompt("bug-spotter", prompt)
-	}()
+	// Rendering runs git, which is too slow for Update
+	path := m.bufferPath
+	return func() tea.Msg {
+		prompts, err := council.Prompts(req)
+		return reviewPromptsMsg{path: path, content: req.Content, prompts: prompts, err: err}
+	}
 }
 
-// buildPrompt constructs the prompt from the template
-func (m *Model) buildPrompt(path, filetype string, line, col int, eventType, content string) string {
-	return fmt.Sprintf(`You are watching a live coding session. The user is editing:
-File: %s (%s)
-Cursor: line %d, col %d
-
-Current buffer content:
-`+"`"+`%s
-%s
-`+"`"+`
-
-Event: %s
+// handleReviewPrompts sends the prompts rendered for a buffer event
+func (m *Model) handleReviewPrompts(msg reviewPromptsMsg) tea.Cmd {
+	if msg.err != nil {
+		logger.Warn("prompt template failed, using the built-in one", "err", msg.err)
+		m.lastError = msg.err.Error()
+	}
+	m.stopUnprompted(council.Agents, msg.prompts)
 
-Provide brief, actionable observations (2-3 sentences max).`,
-		path, filetype, line, col, filetype, content, eventType)
+	cmds := make([]tea.Cmd, 0, len(msg.prompts))
+	for _, p := range msg.prompts {
+		cmds = append(cmds, m.promptAgent(p.Agent, msg.path, p.Prompt, msg.content))
+	}
+	return tea.Batch(cmds...)
 }
 
-// truncateAroundCursor truncates content to keep N lines around the cursor
-func (m *Model) truncateAroundCursor(content string, cursorLine, contextLines int) string {
-	lines := strings.Split(content, "\n")
-	totalLines := len(lines)
-
-	// Calculate start and end indices
-	start := cursorLine - contextLines
-	if start < 0 {
-		start = 0
-	}
-	end := cursorLine + contextLines
-	if end > totalLines {
-		end = totalLines
+// stopUnprompted clears the thinking state of agents left without a prompt
+func (m *Model) stopUnprompted(agents []string, prompts []council.AgentPrompt) {
+	for _, agent := range agents {
+		if !slices.ContainsFunc(prompts, func(p council.AgentPrompt) bool { return p.Agent == agent }) {
+			m.agentThinking[agent] = false
+		}
 	}
+}
 
-	// Build truncated content
-	var result strings.Builder
-	if start > 0 {
-		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", start))
+// backend returns the backend agent runs on, which there is none of when
+// creating the OpenCode client or routing the agents failed
+func (m *Model) backend(agent string) (backend.AgentBackend, error) {
+	if m.backends == nil {
+		return nil, errors.New("no agent backend, see the log")
 	}
-
-	for i := start; i < end; i++ {
-		result.WriteString(lines[i])
-		result.WriteString("\n")
+	if b := m.backends.Backend(agent); b != nil {
+		return b, nil
 	}
+	return nil, errors.New("no backend for the agent")
+}
 
-	if end < totalLines {
-		result.WriteString(fmt.Sprintf("[...%d lines omitted...]\n", totalLines-end))
+// promptAgent sends a prompt to an agent and reports its full reply
+func (m *Model) promptAgent(agent, path, prompt, content string) tea.Cmd {
+	b, err := m.backend(agent)
+	if err != nil {
+		logger.Error("prompting agent", "agent", agent, "err", err)
+		m.agentThinking[agent] = false
+		m.lastError = fmt.Sprintf("%s: %v", agent, err)
+		return nil
+	}
+	return func() tea.Msg {
+		if err := b.EnsureSession(); err != nil {
+			return ErrorMsg{Error: err, Context: agent + " session"}
+		}
+		text, err := b.Prompt(agent, prompt)
+		if err != nil {
+			return ErrorMsg{Error: err, Context: agent}
+		}
+		return AgentResponseMsg{Agent: agent, Path: path, Text: text, Content: content}
 	}
-
-	return result.String()
 }
 
+// The smallest terminal the dashboard fits in
+const (
+	minWidth  = 40
+	minHeight = 12
+)
+
 func (m Model) View() string {
 	if !m.ready {
 		return "Loading..."
 	}
+	if m.width < minWidth || m.height < minHeight {
+		return lipgloss.NewStyle().Width(m.width).Render(
+			fmt.Sprintf("Terminal too small (%dx%d), algopeeps needs %dx%d", m.width, m.height, minWidth, minHeight))
+	}
 
 	header := titleStyle.Render("ALGOPEEPS COUNCIL")
 
 	reviewerCard := components.AgentCard{
 		Name:        "Code Reviewer",
 		Emoji:       "🔍",
-		Output:      m.agents["code_reviewer"],
+		Output:      m.agents["code-reviewer"],
 		Thinking:    m.agentThinking["code-reviewer"],
 		AccentColor: reviewerColor,
 	}
@@ -209,30 +358,29 @@ func (m Model) View() string {
 	bugSpotterCard := components.AgentCard{
 		Name:        "Bug Spotter",
 		Emoji:       "🐛",
-		Output:      m.agents["bug_spotter"],
+		Output:      m.agents["bug-spotter"],
 		Thinking:    m.agentThinking["bug-spotter"],
 		AccentColor: bugSpotterColor,
 	}
 
 	contentWidth := m.width
-	if contentWidth < 40 {
-		contentWidth = 80
-	}
-
 	mainWidth := int(float64(contentWidth) * 0.8)
 	cardWidth := (mainWidth - 4) / 2
 
-	reviewerCardStyled := lipgloss.NewStyle().Width(cardWidth).Render(reviewerCard.Render())
-	bugSpotterCardStyled := lipgloss.NewStyle().Width(cardWidth).Render(bugSpotterCard.Render())
-
-	agentsRow := lipgloss.JoinHorizontal(
-		lipgloss.Top,
-		reviewerCardStyled,
-		"  ",
-		bugSpotterCardStyled,
-	)
+	// Panes shown below the agent cards
+	var panes []string
+	if len(m.pendingEdits) > 0 {
+		panes = append(panes, "", lipgloss.NewStyle().Width(mainWidth).Render(m.editPreview().Render()))
+	}
+	if m.inputActive {
+		panes = append(panes, "", m.renderInput(mainWidth))
+	}
+	if m.logOpen {
+		panes = append(panes, "", m.renderLog(mainWidth))
+	}
 
 	summaryBar := components.SummaryBar{
+		Width:      contentWidth,
 		Filename:   m.bufferFilename,
 		Filetype:   m.bufferFiletype,
 		CursorLine: m.bufferLine,
@@ -247,8 +395,13 @@ func (m Model) View() string {
 	}
 
 	openCodeStatus := lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444")).Render("OpenCode ○")
-	if m.openCodeConnected {
+	switch {
+	case m.backends != nil && !m.backends.Uses(m.ocClient):
+		openCodeStatus = lipgloss.NewStyle().Foreground(dimText).Render("OpenCode unused")
+	case m.openCodeConnected:
 		openCodeStatus = lipgloss.NewStyle().Foreground(connectedColor).Render("OpenCode ●")
+	case m.openCodeState != opencode.StateDisconnected:
+		openCodeStatus = lipgloss.NewStyle().Foreground(lipgloss.Color("#EAB308")).Render("OpenCode ◌ " + m.openCodeState.String())
 	}
 
 	sessionInfo := "No session"
@@ -256,21 +409,55 @@ func (m Model) View() string {
 		sessionInfo = fmt.Sprintf("Session: %s", m.ocClient.SessionID()[:8])
 	}
 
+	logStatus := ""
+	if n := m.unseenLogs(); n > 0 {
+		logStatus = lipgloss.NewStyle().Foreground(lipgloss.Color("#EAB308")).Render(fmt.Sprintf(" | %d new in log", n))
+	}
+
 	errorStatus := ""
 	if m.lastError != "" {
 		errorStatus = lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444")).Render(fmt.Sprintf(" | Error: %s", m.lastError))
+	} else if m.notice != "" {
+		errorStatus = lipgloss.NewStyle().Foreground(connectedColor).Render(" | " + m.notice)
 	}
 
-	statusBar := statusBarStyle.Render(
+	// Cut to the width, the key hints going first
+	statusBar := statusBarStyle.MaxWidth(contentWidth).Render(
 		lipgloss.JoinHorizontal(
 			lipgloss.Left,
 			nvimStatus,
 			lipgloss.NewStyle().Foreground(dimText).Render(" | "),
 			openCodeStatus,
-			lipgloss.NewStyle().Foreground(dimText).Render(fmt.Sprintf(" | %s | Press 'q' to quit", sessionInfo)),
+			lipgloss.NewStyle().Foreground(dimText).Render(" | "+sessionInfo),
+			logStatus,
 			errorStatus,
+			lipgloss.NewStyle().Foreground(dimText).Render(" | Press '/' to ask, 's' for sessions, 'e' to export, 'l' for log, 'q' to quit"),
 		),
 	)
+	summary := summaryBar.Render()
+
+	// The cards get the rows the rest leaves
+	reviewerCard.Width = cardWidth
+	bugSpotterCard.Width = cardWidth
+	cardHeight := m.height - lipgloss.Height(header) - lipgloss.Height(summary) - lipgloss.Height(statusBar) - 2
+	for _, pane := range panes {
+		cardHeight -= lipgloss.Height(pane)
+	}
+	reviewerCard.Height = cardHeight
+	bugSpotterCard.Height = cardHeight
+
+	agentsRow := lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		reviewerCard.Render(),
+		"  ",
+		bugSpotterCard.Render(),
+	)
+	agentsRow = lipgloss.JoinVertical(lipgloss.Left, append([]string{agentsRow}, panes...)...)
+
+	// The session browser takes the place of the agent cards
+	if m.browser.open {
+		agentsRow = m.renderSessions(mainWidth)
+	}
 
 	return lipgloss.JoinVertical(
 		lipgloss.Left,
@@ -278,7 +465,7 @@ func (m Model) View() string {
 		"",
 		agentsRow,
 		"",
-		summaryBar.Render(),
+		summary,
 		statusBar,
 	)
 }
@@ -0,0 +1,225 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// handleInputKey edits the chat input line while it has focus
+func (m *Model) handleInputKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.inputActive = false
+		m.input = ""
+	case tea.KeyEnter:
+		input := strings.TrimSpace(m.input)
+		m.inputActive = false
+		m.input = ""
+		if input != "" {
+			return m.askAgents(input)
+		}
+	case tea.KeyBackspace:
+		if runes := []rune(m.input); len(runes) > 0 {
+			m.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	}
+	return nil
+}
+
+// askAgents sends a question typed in the TUI to the agent it mentions, or
+// to every agent when it doesn't start with an @mention
+func (m *Model) askAgents(input string) tea.Cmd {
+	agent := ""
+	question := input
+	if strings.HasPrefix(input, "@") {
+		name, rest, _ := strings.Cut(input[1:], " ")
+		agent = name
+		question = strings.TrimSpace(rest)
+	}
+
+	return m.ask(editorQuestion{}, agent, question, council.Request{
+		Path:       m.bufferPath,
+		Filename:   m.bufferFilename,
+		Filetype:   m.bufferFiletype,
+		CursorLine: m.bufferLine,
+		CursorCol:  m.bufferCol,
+		Content:    m.bufferContent,
+	})
+}
+
+// handleQuestion answers a question asked from the editor
+func (m *Model) handleQuestion(msg QuestionMsg) tea.Cmd {
+	return m.ask(editorQuestion{id: msg.ID, conn: msg.Conn}, msg.Agent, msg.Question, council.Request{
+		Path:       msg.Buffer.Path,
+		Filename:   msg.Buffer.Name,
+		Filetype:   msg.Buffer.Filetype,
+		CursorLine: msg.Buffer.Cursor.Line,
+		CursorCol:  msg.Buffer.Cursor.Col,
+		Content:    msg.Buffer.Content,
+		Selection:  msg.Selection,
+	})
+}
+
+// editorQuestion is a question asked from the editor, answered on the
+// connection it came on. The zero value stands for a question typed in the TUI.
+type editorQuestion struct {
+	id   string
+	conn int
+}
+
+// ask sends question to agent, or to every agent when agent is empty. Replies
+// to questions from the editor are streamed back to it.
+func (m *Model) ask(q editorQuestion, agent, question string, req council.Request) tea.Cmd {
+	agents := council.Agents
+	if agent != "" {
+		if !slices.Contains(council.Agents, agent) {
+			m.lastError = fmt.Sprintf("Unknown agent @%s", agent)
+			return m.refuse(q, agent, "unknown agent")
+		}
+		agents = []string{agent}
+	}
+	switch {
+	case question == "":
+		return m.refuse(q, agent, "empty question")
+	case m.ocClient == nil:
+		m.lastError = "Asking agents: OpenCode unavailable"
+		return m.refuse(q, agent, "OpenCode unavailable")
+	}
+
+	header := fmt.Sprintf("› %s\n\n", question)
+	for _, agent := range agents {
+		m.agentThinking[agent] = true
+		m.agents[agent] = header
+		delete(m.replied, agent)
+		if q.id != "" {
+			m.questions[agent] = q
+		}
+	}
+
+	// Rendering runs git, which is too slow for Update
+	return func() tea.Msg {
+		prompts, err := council.QuestionPrompts(agents, req, question)
+		return questionPromptsMsg{question: q, header: header, agents: agents, prompts: prompts, err: err}
+	}
+}
+
+// refuse completes a question from the editor that no agent will answer
+func (m *Model) refuse(q editorQuestion, agent, reason string) tea.Cmd {
+	if q.id == "" {
+		return nil
+	}
+	return m.sendToConn(q.conn, protocol.AnswerMessage{
+		Type:      protocol.MessageAnswer,
+		Timestamp: time.Now(),
+		ID:        q.id,
+		Agent:     agent,
+		Done:      true,
+		Error:     reason,
+	}, "Answering question")
+}
+
+// handleQuestionPrompts sends the prompts rendered for a question
+func (m *Model) handleQuestionPrompts(msg questionPromptsMsg) tea.Cmd {
+	if msg.err != nil {
+		logger.Warn("question template failed, using the built-in one", "err", msg.err)
+		m.lastError = msg.err.Error()
+	}
+	m.stopUnprompted(msg.agents, msg.prompts)
+
+	cmds := make([]tea.Cmd, 0, len(msg.prompts))
+	for _, p := range msg.prompts {
+		cmds = append(cmds, m.chatAgent(msg.question, p.Agent, msg.header, p.Prompt))
+	}
+	return tea.Batch(cmds...)
+}
+
+// chatAgent sends a question to an agent and reports its reply
+func (m *Model) chatAgent(q editorQuestion, agent, header, prompt string) tea.Cmd {
+	b, err := m.backend(agent)
+	return func() tea.Msg {
+		reply := ChatReplyMsg{Agent: agent, Header: header, QuestionID: q.id, Conn: q.conn}
+		if err != nil {
+			reply.Err = err
+			return reply
+		}
+		if err := b.EnsureSession(); err != nil {
+			reply.Err = fmt.Errorf("session: %w", err)
+			return reply
+		}
+		reply.Text, reply.Err = b.Chat(agent, prompt)
+		return reply
+	}
+}
+
+// handleChatReply shows a reply in the agent's card and completes the answer
+// in the editor when the question came from there
+func (m *Model) handleChatReply(msg ChatReplyMsg) tea.Cmd {
+	m.agentThinking[msg.Agent] = false
+	m.replied[msg.Agent] = true
+	if msg.Err != nil {
+		logger.Error("answering question", "agent", msg.Agent, "err", msg.Err)
+		m.lastError = fmt.Sprintf("%s: %v", msg.Agent, msg.Err)
+	} else {
+		m.agents[msg.Agent] = msg.Header + msg.Text
+	}
+
+	if msg.QuestionID == "" {
+		return nil
+	}
+	if m.questions[msg.Agent].id == msg.QuestionID {
+		delete(m.questions, msg.Agent)
+	}
+
+	answer := protocol.AnswerMessage{
+		Type:      protocol.MessageAnswer,
+		Timestamp: time.Now(),
+		ID:        msg.QuestionID,
+		Agent:     msg.Agent,
+		Text:      msg.Text,
+		Done:      true,
+	}
+	if msg.Err != nil {
+		answer.Error = msg.Err.Error()
+	}
+	return m.sendToConn(msg.Conn, answer, "Answering question")
+}
+
+// streamAnswer forwards a streamed chunk to the editor when the agent is
+// answering a question asked from there
+func (m *Model) streamAnswer(agent, text string) tea.Cmd {
+	q, ok := m.questions[agent]
+	if !ok {
+		return nil
+	}
+	return m.sendToConn(q.conn, protocol.AnswerMessage{
+		Type:      protocol.MessageAnswer,
+		Timestamp: time.Now(),
+		ID:        q.id,
+		Agent:     agent,
+		Text:      text,
+	}, "Answering question")
+}
+
+// renderInput renders the chat input line
+func (m Model) renderInput(width int) string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(borderColor).
+		Padding(0, 1).
+		Width(width)
+
+	return style.Render(
+		lipgloss.NewStyle().Foreground(brightText).Render("› " + m.input + "█"),
+	)
+}
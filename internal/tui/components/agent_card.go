@@ -1,6 +1,8 @@
 package components
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -10,6 +12,8 @@ type AgentCard struct {
 	Output      string
 	Thinking    bool
 	AccentColor lipgloss.Color
+	Width       int // Total width, borders included; the card fits its content when 0
+	Height      int // Most rows the card may take, borders included; output past them is cut
 }
 
 func (a AgentCard) Render() string {
@@ -28,11 +32,19 @@ func (a AgentCard) Render() string {
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(a.AccentColor).
 		Padding(1, 2)
+	if a.Width > 0 {
+		// Wrap the output inside the borders rather than around them
+		cardStyle = cardStyle.Width(a.Width - cardStyle.GetHorizontalBorderSize())
+	}
 
 	content := a.Output
 	if a.Thinking {
 		content = "Thinking..."
 	}
+	if a.Width > 0 && a.Height > 0 {
+		content = clipLines(contentStyle.Width(a.Width-cardStyle.GetHorizontalFrameSize()).Render(content),
+			a.Height-cardStyle.GetVerticalFrameSize()-lipgloss.Height(titleStyle.Render(title)))
+	}
 
 	contentSection := lipgloss.NewStyle().
 		Width(0).
@@ -46,3 +58,14 @@ func (a AgentCard) Render() string {
 		),
 	)
 }
+
+// clipLines keeps the first n lines of s, ending with … when lines were cut
+func clipLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= max(n, 1) {
+		return s
+	}
+	lines = lines[:max(n, 1)]
+	lines[len(lines)-1] = "…"
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,58 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type EditPreview struct {
+	Agent     string
+	Path      string
+	StartLine int
+	EndLine   int
+	Old       []string
+	New       []string
+	Pending   int
+}
+
+func (e EditPreview) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3F3F46")).
+		Padding(0, 1)
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Bold(true)
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#71717A"))
+	removedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444"))
+	addedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#22C55E"))
+
+	title := fmt.Sprintf("✏️  Suggested edit from %s (%d pending)", e.Agent, e.Pending)
+	location := fmt.Sprintf("%s L%d-L%d", e.Path, e.StartLine, e.EndLine)
+
+	var diff strings.Builder
+	for _, line := range e.Old {
+		diff.WriteString(removedStyle.Render("- " + line))
+		diff.WriteString("\n")
+	}
+	for _, line := range e.New {
+		diff.WriteString(addedStyle.Render("+ " + line))
+		diff.WriteString("\n")
+	}
+
+	return style.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			titleStyle.Render(title),
+			dimStyle.Render(location),
+			"",
+			strings.TrimSuffix(diff.String(), "\n"),
+			"",
+			dimStyle.Render("[a] apply  [d] discard"),
+		),
+	)
+}
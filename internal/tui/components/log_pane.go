@@ -0,0 +1,76 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type LogRow struct {
+	Time      time.Time
+	Level     string
+	Error     bool // Shown in red rather than yellow
+	Component string
+	Message   string
+}
+
+type LogPane struct {
+	Rows  []LogRow // Oldest first
+	Path  string   // Log file, for the full story
+	Width int
+}
+
+func (l LogPane) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3F3F46")).
+		Padding(0, 1)
+	if l.Width > 0 {
+		style = style.Width(l.Width - style.GetHorizontalBorderSize())
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#71717A"))
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#EAB308"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#EF4444"))
+
+	// What's left of a row for the message
+	messageWidth := max(l.Width-style.GetHorizontalFrameSize()-len("15:04:05 ERROR opencode "), 10)
+
+	var rows strings.Builder
+	if len(l.Rows) == 0 {
+		rows.WriteString(dimStyle.Render("No warnings or errors"))
+	}
+	for i, r := range l.Rows {
+		levelStyle := warnStyle
+		if r.Error {
+			levelStyle = errorStyle
+		}
+		if i > 0 {
+			rows.WriteString("\n")
+		}
+		rows.WriteString(fmt.Sprintf("%s %s %s %s",
+			dimStyle.Render(r.Time.Format("15:04:05")),
+			levelStyle.Render(fmt.Sprintf("%-5s", r.Level)),
+			dimStyle.Render(fmt.Sprintf("%-8s", r.Component)),
+			truncate(r.Message, messageWidth)))
+	}
+
+	footer := "[l] close"
+	if l.Path != "" {
+		footer = fmt.Sprintf("[l] close · full log in %s", l.Path)
+	}
+
+	return style.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			titleStyle.Render("📜 Log"),
+			rows.String(),
+			dimStyle.Render(footer),
+		),
+	)
+}
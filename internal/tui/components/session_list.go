@@ -0,0 +1,103 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type SessionRow struct {
+	ID       string
+	Title    string
+	Project  string
+	Updated  time.Time
+	Messages int // Negative while not counted
+	Current  bool
+}
+
+type SessionList struct {
+	Sessions []SessionRow
+	Cursor   int
+	Loading  bool
+	Prompt   string // Rename input or delete confirmation shown under the list
+	Width    int
+}
+
+func (l SessionList) Render() string {
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#3F3F46")).
+		Padding(0, 1)
+	if l.Width > 0 {
+		style = style.Width(l.Width)
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#FAFAFA")).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#71717A"))
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#A855F7")).Bold(true)
+	currentStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#22C55E"))
+
+	var rows strings.Builder
+	switch {
+	case l.Loading:
+		rows.WriteString(dimStyle.Render("Loading sessions..."))
+	case len(l.Sessions) == 0:
+		rows.WriteString(dimStyle.Render("No algopeeps sessions"))
+	}
+
+	for i, s := range l.Sessions {
+		marker := "  "
+		if i == l.Cursor {
+			marker = "› "
+		}
+		messages := ""
+		if s.Messages >= 0 {
+			messages = fmt.Sprintf("%d msgs", s.Messages)
+		}
+		line := fmt.Sprintf("%s%-40s %-20s %s  %9s  %s",
+			marker, truncate(s.Title, 40), truncate(s.Project, 20),
+			s.Updated.Format("2006-01-02 15:04"), messages, s.ID[:min(8, len(s.ID))])
+
+		switch {
+		case i == l.Cursor:
+			line = selectedStyle.Render(line)
+		case s.Current:
+			line = currentStyle.Render(line)
+		}
+		if s.Current {
+			line += currentStyle.Render(" ●")
+		}
+		if i > 0 {
+			rows.WriteString("\n")
+		}
+		rows.WriteString(line)
+	}
+
+	footer := dimStyle.Render("enter resume · r rename · d delete · esc back")
+	if l.Prompt != "" {
+		footer = l.Prompt
+	}
+
+	return style.Render(
+		lipgloss.JoinVertical(
+			lipgloss.Left,
+			titleStyle.Render("🗂  Council sessions"),
+			"",
+			rows.String(),
+			"",
+			footer,
+		),
+	)
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
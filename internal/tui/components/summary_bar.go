@@ -12,6 +12,7 @@ type SummaryBar struct {
 	CursorCol  int
 	LineCount  int
 	LastEvent  string
+	Width      int // Most columns the bar may take; the text is cut to fit
 }
 
 func (s SummaryBar) Render() string {
@@ -37,5 +38,8 @@ func (s SummaryBar) Render() string {
 	text := fmt.Sprintf("📄 %s (%s) | Line %d, Col %d | %d lines | %s",
 		filename, filetype, s.CursorLine, s.CursorCol, s.LineCount, lastEvent)
 
+	if s.Width > 0 {
+		style = style.MaxWidth(s.Width)
+	}
 	return style.Render(text)
 }
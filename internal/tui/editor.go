@@ -0,0 +1,215 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+	"github.com/abhirupda/algopeeps/internal/tui/components"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// EditorSink delivers server→client messages to the connected editors
+type EditorSink interface {
+	Broadcast(msg any) error
+	// Send delivers msg to the editor on connection conn only
+	Send(conn int, msg any) error
+}
+
+// pendingEdit is a proposed edit waiting for the user, anchored to the lines
+// it replaces
+type pendingEdit struct {
+	protocol.EditProposal
+	anchor string
+}
+
+// SetEditorSink sets where confirmed edits are sent
+func (m *Model) SetEditorSink(sink EditorSink) {
+	m.editor = sink
+}
+
+// applyEdit pushes the first pending edit to the editor. It stays pending
+// until the editor has it, see handleEditSent.
+func (m *Model) applyEdit() tea.Cmd {
+	if len(m.pendingEdits) == 0 || m.sendingEdit {
+		return nil
+	}
+	if m.editor == nil {
+		m.lastError = "Applying edit: no editor connected"
+		return nil
+	}
+
+	edit := m.pendingEdits[0]
+	editor := m.editor
+	m.sendingEdit = true
+	return func() tea.Msg {
+		return editSentMsg{edit: edit, err: editor.Broadcast(protocol.EditMessage{
+			Type:      protocol.MessageEdit,
+			Timestamp: time.Now(),
+			Edit:      edit.EditProposal,
+		})}
+	}
+}
+
+// handleEditSent drops an edit the editor got from the pending ones and keeps
+// the local copy of the buffer and the remaining proposals in sync with it.
+// An edit that failed to send stays pending.
+func (m *Model) handleEditSent(msg editSentMsg) {
+	m.sendingEdit = false
+	if msg.err != nil {
+		logger.Error("applying edit", "err", msg.err)
+		m.lastError = fmt.Sprintf("Applying edit: %v", msg.err)
+		return
+	}
+
+	// The edit may have been discarded meanwhile, or superseded by a buffer
+	// event carrying the edited content
+	if len(m.pendingEdits) == 0 || m.pendingEdits[0] != msg.edit {
+		return
+	}
+	m.pendingEdits = shiftEdits(m.pendingEdits[1:], msg.edit.EditProposal)
+	if msg.edit.Path == m.bufferPath {
+		m.bufferContent = replaceLines(m.bufferContent, msg.edit.EditProposal)
+	}
+}
+
+// sendToEditor broadcasts msg to the connected editors in the background
+func (m *Model) sendToEditor(msg any, context string) tea.Cmd {
+	editor := m.editor
+	if editor == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := editor.Broadcast(msg); err != nil {
+			return ErrorMsg{Error: err, Context: context}
+		}
+		return nil
+	}
+}
+
+// sendToConn sends msg to the editor on connection conn in the background
+func (m *Model) sendToConn(conn int, msg any, context string) tea.Cmd {
+	editor := m.editor
+	if editor == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if err := editor.Send(conn, msg); err != nil {
+			return ErrorMsg{Error: err, Context: context}
+		}
+		return nil
+	}
+}
+
+// replaceEdits sets the edits agent proposes for path, anchored to content,
+// the buffer it was shown
+func (m *Model) replaceEdits(agent, path, content string, edits []protocol.EditProposal) {
+	m.pendingEdits = slices.DeleteFunc(m.pendingEdits, func(e pendingEdit) bool {
+		return e.Agent == agent && e.Path == path
+	})
+	for _, e := range edits {
+		m.pendingEdits = append(m.pendingEdits, pendingEdit{EditProposal: e, anchor: findings.EditAnchor(e, content)})
+	}
+}
+
+// followEdits moves the pending edits for path to where the lines they
+// replace are in content, and drops the ones whose lines are gone
+func (m *Model) followEdits(path, content string) {
+	kept := make([]pendingEdit, 0, len(m.pendingEdits))
+	for _, e := range m.pendingEdits {
+		if e.Path == path {
+			var ok bool
+			if e.EditProposal, ok = findings.RelocateEdit(e.EditProposal, e.anchor, content); !ok {
+				continue
+			}
+		}
+		kept = append(kept, e)
+	}
+	m.pendingEdits = kept
+}
+
+// discardEdit drops the first pending edit
+func (m *Model) discardEdit() {
+	if len(m.pendingEdits) > 0 {
+		m.pendingEdits = m.pendingEdits[1:]
+	}
+}
+
+// editPreview builds the diff preview for the first pending edit
+func (m *Model) editPreview() components.EditPreview {
+	edit := m.pendingEdits[0]
+
+	var old []string
+	if edit.Path == m.bufferPath {
+		lines := strings.Split(m.bufferContent, "\n")
+		start := min(edit.Range.Start.Line-1, len(lines))
+		end := min(edit.Range.End.Line, len(lines))
+		old = lines[start:end]
+	}
+
+	return components.EditPreview{
+		Agent:     edit.Agent,
+		Path:      edit.Path,
+		StartLine: edit.Range.Start.Line,
+		EndLine:   edit.Range.End.Line,
+		Old:       old,
+		New:       strings.Split(edit.Text, "\n"),
+		Pending:   len(m.pendingEdits),
+	}
+}
+
+// replaceLines applies a line-wise edit to content
+func replaceLines(content string, edit protocol.EditProposal) string {
+	lines := strings.Split(content, "\n")
+	start := min(edit.Range.Start.Line-1, len(lines))
+	end := min(edit.Range.End.Line, len(lines))
+
+	result := make([]string, 0, len(lines))
+	result = append(result, lines[:start]...)
+	result = append(result, strings.Split(edit.Text, "\n")...)
+	result = append(result, lines[end:]...)
+	return strings.Join(result, "\n")
+}
+
+// shiftEdits moves the edits below an applied edit by the number of lines it
+// added or removed, and drops the ones that overlapped it
+func shiftEdits(edits []pendingEdit, applied protocol.EditProposal) []pendingEdit {
+	delta := strings.Count(applied.Text, "\n") + 1 -
+		(applied.Range.End.Line - applied.Range.Start.Line + 1)
+
+	result := make([]pendingEdit, 0, len(edits))
+	for _, e := range edits {
+		if e.Path != applied.Path {
+			result = append(result, e)
+			continue
+		}
+		if e.Range.End.Line < applied.Range.Start.Line {
+			result = append(result, e)
+			continue
+		}
+		if e.Range.Start.Line <= applied.Range.End.Line {
+			continue
+		}
+		e.Range.Start.Line += delta
+		e.Range.End.Line += delta
+		result = append(result, e)
+	}
+	return result
+}
+
+// publishDiagnostics sends the current findings for path to the editor
+func (m *Model) publishDiagnostics(path string) tea.Cmd {
+	if !m.nvimConnected || path == "" {
+		return nil
+	}
+
+	return m.sendToEditor(protocol.DiagnosticsMessage{
+		Type:        protocol.MessageDiagnostics,
+		Timestamp:   time.Now(),
+		Path:        path,
+		Diagnostics: findings.Diagnostics(m.findings.File(path)),
+	}, "Publishing diagnostics")
+}
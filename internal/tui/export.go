@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abhirupda/algopeeps/internal/export"
+	"github.com/abhirupda/algopeeps/internal/findings"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SetReportsDir sets where findings are exported to
+func (m *Model) SetReportsDir(dir string) {
+	m.reportsDir = dir
+}
+
+// exportFindings writes the current findings in every export format, named
+// after the time of the export
+func (m *Model) exportFindings() tea.Cmd {
+	dir := m.reportsDir
+	if dir == "" {
+		m.lastError = "Exporting findings: no reports directory"
+		return nil
+	}
+	fs := m.findings.All()
+
+	return func() tea.Msg {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return ErrorMsg{Error: err, Context: "Exporting findings"}
+		}
+		base := filepath.Join(dir, "findings-"+time.Now().Format("20060102-150405"))
+		var exts []string
+		for _, format := range export.Formats {
+			if err := writeReport(base+format.Ext(), format, fs); err != nil {
+				return ErrorMsg{Error: err, Context: "Exporting findings"}
+			}
+			exts = append(exts, strings.TrimPrefix(format.Ext(), "."))
+		}
+		return ExportedMsg{
+			Path:  fmt.Sprintf("%s.{%s}", base, strings.Join(exts, ",")),
+			Count: len(fs),
+		}
+	}
+}
+
+func writeReport(path string, format export.Format, fs []findings.Finding) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(f, format, fs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"path/filepath"
+
+	"github.com/abhirupda/algopeeps/internal/findings"
+	"github.com/abhirupda/algopeeps/internal/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SetHistory restores the agents' latest responses and findings from h and
+// records new ones there
+func (m *Model) SetHistory(h *history.Store) {
+	m.history = h
+	if h == nil {
+		return
+	}
+
+	if m.ocClient != nil {
+		m.ocClient.SetProject(filepath.Base(h.Root()))
+	}
+
+	state := h.State()
+	if m.ocClient != nil && state.SessionID != "" {
+		m.ocClient.Reattach(state.SessionID)
+	}
+	for agent, res := range state.Responses {
+		m.agents[agent] = res.Text
+	}
+	for path, agents := range state.Findings {
+		for agent, fs := range agents {
+			m.findings.Replace(path, agent, fs)
+		}
+	}
+}
+
+// saveResponse records an agent's reply, and the session it came from, in
+// the background
+func (m *Model) saveResponse(agent, path, text string, fs []findings.Finding) tea.Cmd {
+	h := m.history
+	if h == nil {
+		return nil
+	}
+	sessionID := ""
+	if m.ocClient != nil {
+		sessionID = m.ocClient.SessionID()
+	}
+	return func() tea.Msg {
+		if err := h.SetSession(sessionID); err != nil {
+			return ErrorMsg{Error: err, Context: "Saving history"}
+		}
+		if err := h.SaveResponse(agent, path, text, fs); err != nil {
+			return ErrorMsg{Error: err, Context: "Saving history"}
+		}
+		return nil
+	}
+}
+
+// saveFindings records the findings for path after an edit moved them, in the
+// background
+func (m *Model) saveFindings(path string) tea.Cmd {
+	h := m.history
+	if h == nil {
+		return nil
+	}
+	byAgent := m.findings.Agents(path)
+	return func() tea.Msg {
+		if err := h.SaveFindings(path, byAgent); err != nil {
+			return ErrorMsg{Error: err, Context: "Saving history"}
+		}
+		return nil
+	}
+}
+
+// saveSession records the session the client is attached to
+func (m *Model) saveSession() tea.Cmd {
+	h := m.history
+	if h == nil || m.ocClient == nil {
+		return nil
+	}
+	sessionID := m.ocClient.SessionID()
+	return func() tea.Msg {
+		if err := h.SetSession(sessionID); err != nil {
+			return ErrorMsg{Error: err, Context: "Saving history"}
+		}
+		return nil
+	}
+}
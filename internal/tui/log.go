@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"log/slog"
+
+	"github.com/abhirupda/algopeeps/internal/logging"
+	"github.com/abhirupda/algopeeps/internal/tui/components"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+var logger = logging.Component("tui")
+
+// logPaneRows is how many of the latest entries the log pane shows
+const logPaneRows = 8
+
+// SetLog shows the warnings and errors kept by recent in the log pane, and
+// points to the full log at path
+func (m *Model) SetLog(recent *logging.Recent, path string) {
+	m.log = recent
+	m.logPath = path
+}
+
+// WatchLog has p redraw the dashboard whenever a warning or error is logged
+func (m *Model) WatchLog(p *tea.Program) {
+	if m.log == nil {
+		return
+	}
+	m.log.Notify(func(logging.Entry) {
+		// Logging happens in Update too, where sending would block
+		go p.Send(LogMsg{})
+	})
+}
+
+// toggleLog opens or closes the log pane, marking everything in it as seen
+func (m *Model) toggleLog() {
+	if m.log == nil {
+		m.lastError = "Log: logging is off"
+		return
+	}
+	m.logOpen = !m.logOpen
+	m.logSeen = m.log.Total()
+}
+
+// unseenLogs counts the warnings and errors logged since the pane was last
+// looked at
+func (m Model) unseenLogs() int {
+	if m.log == nil || m.logOpen {
+		return 0
+	}
+	return m.log.Total() - m.logSeen
+}
+
+// renderLog draws the log pane with the latest entries
+func (m Model) renderLog(width int) string {
+	entries := m.log.Entries()
+	entries = entries[max(len(entries)-logPaneRows, 0):]
+
+	rows := make([]components.LogRow, len(entries))
+	for i, e := range entries {
+		message := e.Message
+		if e.Attrs != "" {
+			message += " " + e.Attrs
+		}
+		rows[i] = components.LogRow{
+			Time:      e.Time,
+			Level:     e.Level.String(),
+			Error:     e.Level >= slog.LevelError,
+			Component: e.Component,
+			Message:   message,
+		}
+	}
+	return components.LogPane{Rows: rows, Path: m.logPath, Width: width}.Render()
+}
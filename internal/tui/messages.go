@@ -1,13 +1,21 @@
 package tui
 
+import (
+	"github.com/abhirupda/algopeeps/internal/council"
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/protocol"
+)
+
 type BufferEventMsg struct {
 	Filename   string
+	Path       string
 	Filetype   string
 	CursorLine int
 	CursorCol  int
 	LineCount  int
 	LastEvent  string
 	Content    string
+	Selection  *protocol.Range
 }
 
 type AgentTextMsg struct {
@@ -19,6 +27,35 @@ type AgentIdleMsg struct {
 	Agent string
 }
 
+// AgentResponseMsg carries an agent's complete reply to a prompt
+type AgentResponseMsg struct {
+	Agent   string
+	Path    string
+	Text    string
+	Content string // Buffer content the agent was shown
+}
+
+// QuestionMsg is a question the editor asks the council
+type QuestionMsg struct {
+	ID        string
+	Conn      int // Editor connection the question came on, for the answer
+	Agent     string
+	Question  string
+	Selection *protocol.Range
+	Buffer    protocol.Buffer
+}
+
+// ChatReplyMsg carries an agent's reply to a question asked from the TUI or
+// the editor. QuestionID and Conn are set for questions from the editor.
+type ChatReplyMsg struct {
+	Agent      string
+	Text       string
+	Header     string
+	QuestionID string
+	Conn       int
+	Err        error
+}
+
 type ConnectionStatusMsg struct {
 	Connected bool
 	Source    string
@@ -30,3 +67,48 @@ type ErrorMsg struct {
 }
 
 type startSSEMsg struct{}
+
+// editSentMsg reports whether an applied edit reached the editor
+type editSentMsg struct {
+	edit pendingEdit
+	err  error
+}
+
+// reviewPromptsMsg carries the prompts rendered for a buffer event
+type reviewPromptsMsg struct {
+	path    string
+	content string
+	prompts []council.AgentPrompt
+	err     error
+}
+
+// questionPromptsMsg carries the prompts rendered for a question to agents
+type questionPromptsMsg struct {
+	question editorQuestion
+	header   string
+	agents   []string
+	prompts  []council.AgentPrompt
+	err      error
+}
+
+// LogMsg reports a warning or error was logged, for the log pane
+type LogMsg struct{}
+
+// ExportedMsg reports findings written to disk
+type ExportedMsg struct {
+	Path  string // Files written, as a brace pattern for the formats
+	Count int
+}
+
+// sessionMessagesMsg carries the message count of a session
+type sessionMessagesMsg struct {
+	id    string
+	count int
+	err   error
+}
+
+// SessionsMsg carries the sessions listed for the session browser
+type SessionsMsg struct {
+	Sessions []opencode.SessionInfo
+	Err      error
+}
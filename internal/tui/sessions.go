@@ -0,0 +1,231 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abhirupda/algopeeps/internal/opencode"
+	"github.com/abhirupda/algopeeps/internal/tui/components"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// sessionBrowser is the screen listing past council sessions
+type sessionBrowser struct {
+	open          bool
+	loading       bool
+	sessions      []opencode.SessionInfo
+	cursor        int
+	messages      map[string]int // Message counts of the sessions counted so far
+	renaming      bool
+	confirmDelete bool
+	input         string
+}
+
+// openSessions shows the session browser and loads the sessions
+func (m *Model) openSessions() tea.Cmd {
+	if m.ocClient == nil {
+		m.lastError = "Sessions: no OpenCode client"
+		return nil
+	}
+	m.browser = sessionBrowser{open: true, loading: true}
+	return m.loadSessions()
+}
+
+func (m *Model) loadSessions() tea.Cmd {
+	client := m.ocClient
+	return func() tea.Msg {
+		sessions, err := client.ListSessions()
+		return SessionsMsg{Sessions: sessions, Err: err}
+	}
+}
+
+// handleSessionsMsg shows a freshly loaded session list
+func (m *Model) handleSessionsMsg(msg SessionsMsg) tea.Cmd {
+	m.browser.loading = false
+	if msg.Err != nil {
+		logger.Error("listing sessions", "err", msg.Err)
+		m.lastError = fmt.Sprintf("Sessions: %v", msg.Err)
+		return nil
+	}
+	m.browser.sessions = msg.Sessions
+	m.browser.cursor = min(m.browser.cursor, max(len(msg.Sessions)-1, 0))
+	// Counts change as sessions are used
+	m.browser.messages = make(map[string]int)
+	return m.countMessages()
+}
+
+// countMessages counts the messages of every listed session. Counting means
+// fetching them, so the list is shown first and the counts fill in as they
+// arrive.
+func (m *Model) countMessages() tea.Cmd {
+	client := m.ocClient
+	cmds := make([]tea.Cmd, 0, len(m.browser.sessions))
+	for _, s := range m.browser.sessions {
+		cmds = append(cmds, func() tea.Msg {
+			n, err := client.MessageCount(s.ID)
+			return sessionMessagesMsg{id: s.ID, count: n, err: err}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+// handleSessionMessages shows the message count of a session
+func (m *Model) handleSessionMessages(msg sessionMessagesMsg) {
+	if msg.err != nil {
+		logger.Warn("counting session messages", "session", msg.id, "err", msg.err)
+		return
+	}
+	if m.browser.messages != nil {
+		m.browser.messages[msg.id] = msg.count
+	}
+}
+
+// handleBrowserKey drives the session browser while it is open
+func (m *Model) handleBrowserKey(msg tea.KeyMsg) tea.Cmd {
+	b := &m.browser
+	if b.renaming {
+		return m.handleRenameKey(msg)
+	}
+	if b.confirmDelete {
+		b.confirmDelete = false
+		if msg.String() == "y" {
+			return m.deleteSession()
+		}
+		return nil
+	}
+
+	switch msg.String() {
+	case "esc", "q", "s":
+		m.browser = sessionBrowser{}
+	case "up", "k":
+		b.cursor = max(b.cursor-1, 0)
+	case "down", "j":
+		b.cursor = min(b.cursor+1, max(len(b.sessions)-1, 0))
+	case "enter":
+		return m.resumeSession()
+	case "r":
+		if s, ok := m.selectedSession(); ok {
+			b.renaming = true
+			b.input = s.Title
+		}
+	case "d":
+		if _, ok := m.selectedSession(); ok {
+			b.confirmDelete = true
+		}
+	}
+	return nil
+}
+
+// handleRenameKey edits the new title of the selected session
+func (m *Model) handleRenameKey(msg tea.KeyMsg) tea.Cmd {
+	b := &m.browser
+	switch msg.Type {
+	case tea.KeyEsc:
+		b.renaming = false
+		b.input = ""
+	case tea.KeyEnter:
+		title := strings.TrimSpace(b.input)
+		b.renaming = false
+		b.input = ""
+		s, ok := m.selectedSession()
+		if !ok || title == "" || title == s.Title {
+			return nil
+		}
+		client := m.ocClient
+		return func() tea.Msg {
+			if err := client.RenameSession(s.ID, s.Project, title); err != nil {
+				return ErrorMsg{Error: err, Context: "Renaming session"}
+			}
+			sessions, err := client.ListSessions()
+			return SessionsMsg{Sessions: sessions, Err: err}
+		}
+	case tea.KeyBackspace:
+		if runes := []rune(b.input); len(runes) > 0 {
+			b.input = string(runes[:len(runes)-1])
+		}
+	case tea.KeySpace:
+		b.input += " "
+	case tea.KeyRunes:
+		b.input += string(msg.Runes)
+	}
+	return nil
+}
+
+func (m *Model) selectedSession() (opencode.SessionInfo, bool) {
+	b := m.browser
+	if b.cursor < 0 || b.cursor >= len(b.sessions) {
+		return opencode.SessionInfo{}, false
+	}
+	return b.sessions[b.cursor], true
+}
+
+// resumeSession switches the council to the selected session
+func (m *Model) resumeSession() tea.Cmd {
+	s, ok := m.selectedSession()
+	if !ok {
+		return nil
+	}
+	m.browser = sessionBrowser{}
+
+	// The client lifecycle picks the session up and reports it
+	m.ocClient.Reattach(s.ID)
+	return nil
+}
+
+func (m *Model) deleteSession() tea.Cmd {
+	s, ok := m.selectedSession()
+	if !ok {
+		return nil
+	}
+	client := m.ocClient
+	return func() tea.Msg {
+		if err := client.DeleteSession(s.ID); err != nil {
+			return ErrorMsg{Error: err, Context: "Deleting session"}
+		}
+		sessions, err := client.ListSessions()
+		return SessionsMsg{Sessions: sessions, Err: err}
+	}
+}
+
+// renderSessions renders the session browser
+func (m Model) renderSessions(width int) string {
+	b := m.browser
+	current := ""
+	if m.ocClient != nil {
+		current = m.ocClient.SessionID()
+	}
+
+	rows := make([]components.SessionRow, 0, len(b.sessions))
+	for _, s := range b.sessions {
+		messages, ok := b.messages[s.ID]
+		if !ok {
+			messages = -1
+		}
+		rows = append(rows, components.SessionRow{
+			ID:       s.ID,
+			Title:    s.Title,
+			Project:  s.Project,
+			Updated:  s.Updated,
+			Messages: messages,
+			Current:  s.ID == current,
+		})
+	}
+
+	prompt := ""
+	switch {
+	case b.renaming:
+		prompt = "New title: " + b.input + "█"
+	case b.confirmDelete:
+		if s, ok := m.selectedSession(); ok {
+			prompt = fmt.Sprintf("Delete %q? y/n", s.Title)
+		}
+	}
+
+	return components.SessionList{
+		Sessions: rows,
+		Cursor:   b.cursor,
+		Loading:  b.loading,
+		Prompt:   prompt,
+		Width:    width,
+	}.Render()
+}
@@ -0,0 +1,122 @@
+-- ask.lua - Ask the council a question and show the streamed answers
+
+local client = require('algopeeps.client')
+
+local M = {}
+
+local answer_buf = nil
+local counter = 0
+
+-- Answers per question ID, per agent, in arrival order
+local answers = {}
+
+--- Get the answers buffer, showing it in a split if it isn't visible
+--- @return number Buffer handle
+local function ensure_buffer()
+  if not answer_buf or not vim.api.nvim_buf_is_valid(answer_buf) then
+    answer_buf = vim.api.nvim_create_buf(false, true)
+    vim.api.nvim_buf_set_name(answer_buf, 'algopeeps://answers')
+    vim.bo[answer_buf].filetype = 'markdown'
+  end
+
+  if vim.fn.bufwinid(answer_buf) == -1 then
+    local win = vim.api.nvim_get_current_win()
+    vim.cmd('botright split')
+    vim.api.nvim_win_set_buf(0, answer_buf)
+    vim.api.nvim_set_current_win(win)
+  end
+
+  return answer_buf
+end
+
+--- Render every answer to a question into the answers buffer
+--- @param id string Question ID
+local function render(id)
+  local question = answers[id]
+  if not question then
+    return
+  end
+
+  local lines = { '# ' .. question.text, '' }
+  for _, agent in ipairs(question.order) do
+    local answer = question.agents[agent]
+    table.insert(lines, '## ' .. agent .. (answer.done and '' or ' …'))
+    vim.list_extend(lines, vim.split(answer.text, '\n', { plain = true }))
+    table.insert(lines, '')
+  end
+
+  local buf = ensure_buffer()
+  vim.api.nvim_buf_set_lines(buf, 0, -1, false, lines)
+end
+
+--- Send a question to the council
+--- @param question string The question
+--- @param agent string|nil Agent to ask, nil for every agent
+--- @param selection table|nil Selected line range { start_line, end_line } (1-based)
+function M.ask(question, agent, selection)
+  if not client.is_connected() then
+    vim.notify('Not connected to algopeeps', vim.log.levels.WARN)
+    return
+  end
+
+  counter = counter + 1
+  local id = string.format('%d-%d', os.time(), counter)
+  answers[id] = { text = question, order = {}, agents = {} }
+
+  local msg = {
+    type = 'question',
+    timestamp = os.date('!%Y-%m-%dT%H:%M:%SZ'),
+    id = id,
+    agent = agent,
+    question = question,
+    buffer = client.buffer_info(),
+  }
+  if selection then
+    msg.selection = {
+      start = { line = selection[1], col = 0 },
+      ['end'] = { line = selection[2], col = 0 },
+    }
+  end
+
+  client.send(msg)
+  render(id)
+end
+
+--- Handle an answer message streamed by the server
+--- @param msg table Decoded answer message
+function M.on_answer(msg)
+  local question = answers[msg.id]
+  if not question then
+    return
+  end
+
+  local answer = question.agents[msg.agent]
+  if not answer then
+    answer = { text = '', done = false }
+    question.agents[msg.agent] = answer
+    table.insert(question.order, msg.agent)
+  end
+
+  if msg.done then
+    -- The final message carries the complete answer
+    answer.text = msg.error and ('Error: ' .. msg.error) or msg.text
+    answer.done = true
+  else
+    answer.text = answer.text .. msg.text
+  end
+
+  render(msg.id)
+end
+
+--- Parse ":AlgopeepsAsk [@agent] question" arguments
+--- @param args string Command arguments
+--- @return string|nil agent, string question
+function M.parse_args(args)
+  local agent, rest = args:match('^@(%S+)%s+(.*)$')
+  if agent then
+    return agent, rest
+  end
+  return nil, args
+end
+
+return M
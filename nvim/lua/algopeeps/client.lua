@@ -9,6 +9,8 @@ local tcp = nil
 local connected = false
 local config = {}
 local debounced_send = nil
+local handlers = {}
+local read_buffer = ''
 
 --- Initialize the client with config
 --- @param opts table Configuration options
@@ -44,6 +46,55 @@ local function collect_buffer_info()
   }
 end
 
+--- Register a handler for messages sent by the server
+--- @param msg_type string Message type (e.g. "edit")
+--- @param fn function Called with the decoded message
+function M.on(msg_type, fn)
+  handlers[msg_type] = fn
+end
+
+--- Dispatch one JSON line received from the server
+--- @param line string Raw JSON line
+local function handle_line(line)
+  local ok, msg = pcall(vim.json.decode, line)
+  if not ok or type(msg) ~= 'table' then
+    return
+  end
+
+  local handler = handlers[msg.type]
+  if handler then
+    handler(msg)
+  end
+end
+
+--- Handle raw data read from the socket, splitting it into lines
+--- @param err string|nil Read error
+--- @param chunk string|nil Data read
+local function on_read(err, chunk)
+  if err or not chunk then
+    return
+  end
+
+  read_buffer = read_buffer .. chunk
+  while true do
+    local nl = read_buffer:find('\n', 1, true)
+    if not nl then
+      break
+    end
+    local line = read_buffer:sub(1, nl - 1)
+    read_buffer = read_buffer:sub(nl + 1)
+    vim.schedule(function()
+      handle_line(line)
+    end)
+  end
+end
+
+--- Current buffer information in the shape the server expects
+--- @return table Buffer info
+function M.buffer_info()
+  return collect_buffer_info()
+end
+
 --- Connect to TCP server
 --- @param host string Host address
 --- @param port number Port number
@@ -66,6 +117,9 @@ function M.connect(host, port)
       return
     end
     
+    read_buffer = ''
+    tcp:read_start(on_read)
+
     vim.schedule(function()
       connected = true
       vim.notify('Connected to algopeeps at ' .. host .. ':' .. port, vim.log.levels.INFO)
@@ -108,12 +162,19 @@ end
 
 --- Send update immediately
 --- @param event_type string Type of event
-function M.send_update(event_type)
+--- @param selection table|nil Selected line range { start_line, end_line } (1-based)
+function M.send_update(event_type, selection)
   if not connected then
     return
   end
   
   local buffer_info = collect_buffer_info()
+  if selection then
+    buffer_info.selection = {
+      start = { line = selection[1], col = 0 },
+      ['end'] = { line = selection[2], col = 0 },
+    }
+  end
   
   -- Format timestamp as ISO8601 to match Go's time.Time JSON serialization
   local timestamp = os.date("!%Y-%m-%dT%H:%M:%SZ")
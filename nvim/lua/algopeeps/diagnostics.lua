@@ -0,0 +1,44 @@
+-- diagnostics.lua - Show agent findings as Neovim diagnostics
+
+local M = {}
+
+local namespace = vim.api.nvim_create_namespace('algopeeps')
+
+local severities = {
+  error = vim.diagnostic.severity.ERROR,
+  warning = vim.diagnostic.severity.WARN,
+  info = vim.diagnostic.severity.INFO,
+  hint = vim.diagnostic.severity.HINT,
+}
+
+--- Replace the diagnostics of a buffer with the ones sent by the server
+--- @param msg table Decoded diagnostics message
+function M.set(msg)
+  local bufnr = vim.fn.bufnr(msg.path)
+  if bufnr == -1 or not vim.api.nvim_buf_is_loaded(bufnr) then
+    return
+  end
+
+  local items = {}
+  for _, d in ipairs(msg.diagnostics or {}) do
+    -- Server lines are 1-based, Neovim diagnostics are 0-based
+    table.insert(items, {
+      lnum = d.range.start.line - 1,
+      col = d.range.start.col,
+      end_lnum = d.range['end'].line - 1,
+      end_col = d.range['end'].col,
+      severity = severities[d.severity] or vim.diagnostic.severity.INFO,
+      source = d.source,
+      message = d.message,
+    })
+  end
+
+  vim.diagnostic.set(namespace, bufnr, items)
+end
+
+--- Clear every algopeeps diagnostic
+function M.reset()
+  vim.diagnostic.reset(namespace)
+end
+
+return M
@@ -0,0 +1,34 @@
+-- edit.lua - Apply agent edit proposals to buffers
+
+local M = {}
+
+--- Apply an edit message from the server as an undoable buffer edit
+--- @param msg table Decoded edit message
+function M.apply(msg)
+  local edit = msg.edit
+  if not edit or not edit.range then
+    return
+  end
+
+  local bufnr = vim.fn.bufnr(edit.path)
+  if bufnr == -1 or not vim.api.nvim_buf_is_loaded(bufnr) then
+    vim.notify('algopeeps: buffer not loaded for ' .. edit.path, vim.log.levels.WARN)
+    return
+  end
+
+  -- Edits are line-wise with 1-based inclusive lines
+  local start_line = edit.range.start.line - 1
+  local end_line = edit.range['end'].line
+  local lines = vim.split(edit.text or '', '\n', { plain = true })
+
+  local ok, err = pcall(vim.api.nvim_buf_set_lines, bufnr, start_line, end_line, false, lines)
+  if not ok then
+    vim.notify('algopeeps: failed to apply edit: ' .. err, vim.log.levels.ERROR)
+    return
+  end
+
+  vim.notify(string.format('algopeeps: applied edit from %s (L%d-L%d)',
+    edit.agent or 'agent', edit.range.start.line, edit.range['end'].line), vim.log.levels.INFO)
+end
+
+return M
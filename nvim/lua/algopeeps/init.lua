@@ -1,6 +1,9 @@
 -- init.lua - Algopeeps Neovim plugin entry point
 
 local client = require('algopeeps.client')
+local edit = require('algopeeps.edit')
+local diagnostics = require('algopeeps.diagnostics')
+local ask = require('algopeeps.ask')
 
 local M = {}
 
@@ -19,6 +22,9 @@ local autocmd_group = nil
 function M.setup(opts)
   config = vim.tbl_deep_extend('force', default_config, opts or {})
   client.init(config)
+  client.on('edit', edit.apply)
+  client.on('diagnostics', diagnostics.set)
+  client.on('answer', ask.on_answer)
   
   -- Create autocmd group
   autocmd_group = vim.api.nvim_create_augroup('Algopeeps', { clear = true })
@@ -93,6 +99,7 @@ end
 function M.disconnect()
   cleanup_autocmds()
   client.disconnect()
+  diagnostics.reset()
 end
 
 --- Create user commands
@@ -108,6 +115,30 @@ local function create_commands()
   end, {
     desc = 'Disconnect from algopeeps server'
   })
+
+  vim.api.nvim_create_user_command('AlgopeepsReview', function(opts)
+    if not client.is_connected() then
+      vim.notify('Not connected to algopeeps', vim.log.levels.WARN)
+      return
+    end
+    client.send_update('review_selection', { opts.line1, opts.line2 })
+  end, {
+    range = true,
+    desc = 'Ask the council to review the selected lines'
+  })
+
+  vim.api.nvim_create_user_command('AlgopeepsAsk', function(opts)
+    local agent, question = ask.parse_args(opts.args)
+    local selection = nil
+    if opts.range > 0 then
+      selection = { opts.line1, opts.line2 }
+    end
+    ask.ask(question, agent, selection)
+  end, {
+    nargs = '+',
+    range = true,
+    desc = 'Ask the council a question ([@agent] question), scoped to the selection if any'
+  })
 end
 
 -- Initialize commands when plugin loads
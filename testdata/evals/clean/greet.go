@@ -0,0 +1,11 @@
+package greet
+
+import "fmt"
+
+// Greeting returns a greeting for name, or a generic one when name is empty
+func Greeting(name string) string {
+	if name == "" {
+		return "Hello!"
+	}
+	return fmt.Sprintf("Hello, %s!", name)
+}
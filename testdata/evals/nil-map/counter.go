@@ -0,0 +1,12 @@
+package words
+
+import "strings"
+
+// Count counts how often each word occurs in text
+func Count(text string) map[string]int {
+	var counts map[string]int
+	for _, word := range strings.Fields(text) {
+		counts[strings.ToLower(word)]++
+	}
+	return counts
+}
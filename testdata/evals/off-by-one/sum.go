@@ -0,0 +1,10 @@
+package stats
+
+// Sum adds up values
+func Sum(values []int) int {
+	total := 0
+	for i := 0; i <= len(values); i++ {
+		total += values[i]
+	}
+	return total
+}
@@ -0,0 +1,17 @@
+package orders
+
+type Order struct {
+	Price    float64
+	Quantity int
+}
+
+func Do(x []Order, y float64) float64 {
+	var z float64
+	for _, a := range x {
+		b := a.Price * float64(a.Quantity)
+		if b > y {
+			z += b
+		}
+	}
+	return z
+}